@@ -0,0 +1,41 @@
+// Command reconcile recounts the votes of every post from the votes table and
+// fixes the denormalized upvotes, downvotes and score columns if they drifted.
+package main
+
+import (
+	"fmt"
+	"github.com/arshamroshannejad/task-rootext/config"
+	"github.com/arshamroshannejad/task-rootext/internal/database"
+	"github.com/arshamroshannejad/task-rootext/internal/logger"
+	"github.com/arshamroshannejad/task-rootext/internal/repository"
+	"github.com/arshamroshannejad/task-rootext/internal/service"
+	"go.uber.org/zap"
+)
+
+func main() {
+	cfg, err := config.New()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize config variables: %v", err))
+	}
+	zapLog, err := logger.New(cfg.App.Debug)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize zap logger: %v", err))
+	}
+	defer zapLog.Sync()
+	db, err := database.OpenDB(cfg)
+	if err != nil {
+		zapLog.Fatal("Failed to connect postgres", zap.Error(err))
+	}
+	defer db.Close()
+	redisDB, err := database.OpenRedis(cfg)
+	if err != nil {
+		zapLog.Fatal("Failed to connect redis", zap.Error(err))
+	}
+	defer redisDB.Close()
+	postService := service.NewPostService(repository.NewPostRepository(db), redisDB, zapLog, cfg)
+	fixed, err := postService.ReconcileVoteCounts()
+	if err != nil {
+		zapLog.Fatal("Failed to reconcile vote counters", zap.Error(err))
+	}
+	zapLog.Info("Vote counters reconciled", zap.Int64("Fixed", fixed))
+}
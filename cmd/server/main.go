@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"github.com/arshamroshannejad/task-rootext/config"
 	"github.com/arshamroshannejad/task-rootext/internal/database"
+	"github.com/arshamroshannejad/task-rootext/internal/jobs"
+	"github.com/arshamroshannejad/task-rootext/internal/keyring"
 	"github.com/arshamroshannejad/task-rootext/internal/logger"
+	"github.com/arshamroshannejad/task-rootext/internal/mailer"
 	"github.com/arshamroshannejad/task-rootext/internal/router"
+	"github.com/arshamroshannejad/task-rootext/internal/storage"
 	"go.uber.org/zap"
 	"net/http"
 	"time"
@@ -26,6 +30,9 @@ import (
 //	@securityDefinitions.apikey	BearerAuth
 //	@in							header
 //	@name						Authorization
+//	@securityDefinitions.apikey	APIKeyAuth
+//	@in							header
+//	@name						X-API-Key
 func main() {
 	cfg, err := config.New()
 	if err != nil {
@@ -48,9 +55,24 @@ func main() {
 	}
 	defer redisDB.Close()
 	zapLog.Info("Redis connected", zap.String("Host", cfg.Redis.Host), zap.Int("Port", cfg.Redis.Port))
+	fileStorage, err := storage.New(cfg)
+	if err != nil {
+		zapLog.Fatal("Failed to initialize file storage", zap.Error(err))
+	}
+	zapLog.Info("File storage initialized", zap.String("Driver", cfg.Storage.Driver))
+	mailSender, err := mailer.New(cfg, zapLog)
+	if err != nil {
+		zapLog.Fatal("Failed to initialize mailer", zap.Error(err))
+	}
+	zapLog.Info("Mailer initialized", zap.String("Driver", cfg.Mail.Driver))
+	keys, err := keyring.New(cfg, zapLog)
+	if err != nil {
+		zapLog.Fatal("Failed to load jwt keys", zap.Error(err))
+	}
+	jobs.Start(db, redisDB, fileStorage, zapLog, cfg)
 	srv := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", cfg.App.Host, cfg.App.Port),
-		Handler:      router.SetupRoutes(db, redisDB, zapLog, cfg),
+		Handler:      router.SetupRoutes(db, redisDB, fileStorage, mailSender, keys, zapLog, cfg),
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
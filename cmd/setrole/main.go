@@ -0,0 +1,72 @@
+// Command setrole changes the role of a user by email, which is how the first
+// admin is created before anyone can use the admin endpoints.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/arshamroshannejad/task-rootext/config"
+	"github.com/arshamroshannejad/task-rootext/internal/database"
+	"github.com/arshamroshannejad/task-rootext/internal/keyring"
+	"github.com/arshamroshannejad/task-rootext/internal/logger"
+	"github.com/arshamroshannejad/task-rootext/internal/mailer"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"github.com/arshamroshannejad/task-rootext/internal/repository"
+	"github.com/arshamroshannejad/task-rootext/internal/service"
+	"go.uber.org/zap"
+	"slices"
+)
+
+func main() {
+	email := flag.String("email", "", "email of the user")
+	role := flag.String("role", model.RoleAdmin, "new role: user, moderator or admin")
+	flag.Parse()
+	if *email == "" || !slices.Contains([]string{model.RoleUser, model.RoleModerator, model.RoleAdmin}, *role) {
+		flag.Usage()
+		return
+	}
+	cfg, err := config.New()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize config variables: %v", err))
+	}
+	zapLog, err := logger.New(cfg.App.Debug)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize zap logger: %v", err))
+	}
+	defer zapLog.Sync()
+	db, err := database.OpenDB(cfg)
+	if err != nil {
+		zapLog.Fatal("Failed to connect postgres", zap.Error(err))
+	}
+	defer db.Close()
+	redisDB, err := database.OpenRedis(cfg)
+	if err != nil {
+		zapLog.Fatal("Failed to connect redis", zap.Error(err))
+	}
+	defer redisDB.Close()
+	mailSender, err := mailer.New(cfg, zapLog)
+	if err != nil {
+		zapLog.Fatal("Failed to initialize mailer", zap.Error(err))
+	}
+	keys, err := keyring.New(cfg, zapLog)
+	if err != nil {
+		zapLog.Fatal("Failed to load jwt keys", zap.Error(err))
+	}
+	userService := service.NewUserService(
+		repository.NewUserRepository(db),
+		repository.NewRefreshTokenRepository(db),
+		repository.NewSessionRepository(db),
+		repository.NewMFARepository(db),
+		repository.NewAccountRepository(db),
+		service.NewPostService(repository.NewPostRepository(db), redisDB, zapLog, cfg),
+		redisDB, mailSender, keys, zapLog, cfg,
+	)
+	user, err := userService.GetUserByEmail(*email)
+	if err != nil {
+		zapLog.Fatal("Failed to find user", zap.String("Email", *email), zap.Error(err))
+	}
+	if _, err := userService.SetUserRole(user.ID, *role); err != nil {
+		zapLog.Fatal("Failed to set user role", zap.Error(err))
+	}
+	zapLog.Info("User role changed", zap.String("Email", *email), zap.String("Role", *role))
+}
@@ -3,7 +3,10 @@ package config
 import (
 	"bytes"
 	_ "embed"
+	"fmt"
 	"github.com/spf13/viper"
+	"net/netip"
+	"strings"
 	"time"
 )
 
@@ -37,12 +40,101 @@ type App struct {
 	RefreshHourTTL time.Duration
 	CorsOrigins    []string
 	CorsMaxAge     int
+	// TrustedProxies are the addresses or CIDR ranges of the reverse proxies
+	// in front of the server. only requests from them may set the client IP
+	// with X-Forwarded-For or X-Real-IP.
+	TrustedProxies []string
+	// TrustedProxyNets is TrustedProxies parsed by New.
+	TrustedProxyNets []netip.Prefix `mapstructure:"-"`
+}
+
+type Ranking struct {
+	RisingWindow          time.Duration
+	RisingRefreshInterval time.Duration
+}
+
+type Posts struct {
+	RestoreWindow   time.Duration
+	PurgeInterval   time.Duration
+	PublishInterval time.Duration
+}
+
+type Storage struct {
+	Driver      string
+	PublicURL   string
+	LocalPath   string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+type Attachments struct {
+	MaxSize         int64
+	MaxPerPost      int
+	AllowedTypes    []string
+	CleanupInterval time.Duration
+}
+
+type Auth struct {
+	RequireVerifiedEmail  bool
+	VerificationTokenTTL  time.Duration
+	VerificationURL       string
+	VerificationCooldown  time.Duration
+	PasswordResetTTL      time.Duration
+	PasswordResetURL      string
+	PasswordResetCooldown time.Duration
+	MFAIssuer             string
+	MFAPendingTTL         time.Duration
+	MFAMaxAttempts        int
+	MFAAttemptWindow      time.Duration
+	LoginMaxAttempts      int
+	LoginIPMaxAttempts    int
+	LoginAttemptWindow    time.Duration
+	LoginBackoffBase      time.Duration
+	LoginLockoutDuration  time.Duration
+}
+
+type JWT struct {
+	KeysDir     string
+	ActiveKeyID string
+	AcceptHS256 bool
+	// InsecureHS256 signs access tokens with HS256 and the app secret when no
+	// keys directory is configured. it is only meant for local development.
+	InsecureHS256 bool
+}
+
+type Mail struct {
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	FilePath     string
 }
 
 type Config struct {
-	Postgres *Postgres
-	Redis    *Redis
-	App      *App
+	Postgres    *Postgres
+	Redis       *Redis
+	App         *App
+	Ranking     *Ranking
+	Posts       *Posts
+	Storage     *Storage
+	Attachments *Attachments
+	Auth        *Auth
+	JWT         *JWT
+	Mail        *Mail
+}
+
+// secretEnv maps settings that must not be committed with the embedded yaml to
+// the environment variables they are read from.
+var secretEnv = map[string]string{
+	"app.secret":        "APP_SECRET",
+	"jwt.keysdir":       "JWT_KEYS_DIR",
+	"jwt.activekeyid":   "JWT_ACTIVE_KEY_ID",
+	"jwt.insecurehs256": "JWT_INSECURE_HS256",
 }
 
 func New() (*Config, error) {
@@ -50,9 +142,42 @@ func New() (*Config, error) {
 	if err := viper.ReadConfig(bytes.NewBuffer(Configurations)); err != nil {
 		return nil, err
 	}
+	for key, env := range secretEnv {
+		if err := viper.BindEnv(key, env); err != nil {
+			return nil, err
+		}
+	}
 	var cfg Config
 	if err := viper.Unmarshal(&cfg); err != nil {
 		return nil, err
 	}
+	trustedProxyNets, err := parsePrefixes(cfg.App.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	cfg.App.TrustedProxyNets = trustedProxyNets
 	return &cfg, nil
 }
+
+// parsePrefixes parses a list of CIDR ranges, where a single address stands
+// for a range of its own.
+func parsePrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
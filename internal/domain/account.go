@@ -0,0 +1,13 @@
+package domain
+
+import "github.com/arshamroshannejad/task-rootext/internal/model"
+
+// AccountRepository reads everything a user owns for the data export and
+// removes it when the account is deleted.
+type AccountRepository interface {
+	GetPosts(userID string) ([]model.Post, error)
+	GetComments(userID string) ([]model.Comment, error)
+	GetPostVotes(userID string) ([]model.PostVote, error)
+	GetCommentVotes(userID string) ([]model.CommentVote, error)
+	Anonymize(userID string) (int, error)
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"errors"
+	"github.com/arshamroshannejad/task-rootext/internal/entities"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"time"
+)
+
+var ErrAPIKeyInvalid = errors.New("api key is invalid or expired")
+
+type APIKeyRepository interface {
+	GetByUser(userID string) (*[]model.APIKey, error)
+	GetByID(userID, keyID string) (*model.APIKey, error)
+	GetByHash(tokenHash string) (*model.APIKey, error)
+	Create(userID, name, prefix, tokenHash string, scopes []string, expiresAt *time.Time) (*model.APIKey, error)
+	Update(userID, keyID, name string, scopes []string) (*model.APIKey, error)
+	Delete(userID, keyID string) error
+	Touch(keyID string) error
+}
+
+type APIKeyService interface {
+	GetUserAPIKeys(userID string) (*[]model.APIKey, error)
+	GetAPIKeyByID(userID, keyID string) (*model.APIKey, error)
+	CreateAPIKey(userID string, apiKey *entities.APIKeyCreateRequest) (*model.CreatedAPIKey, error)
+	UpdateAPIKey(userID, keyID string, apiKey *entities.APIKeyUpdateRequest) (*model.APIKey, error)
+	DeleteAPIKey(userID, keyID string) error
+	Authenticate(token string) (*model.APIKey, *model.User, error)
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"errors"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"io"
+)
+
+var ErrTooManyAttachments = errors.New("post has reached the maximum number of attachments")
+
+type AttachmentRepository interface {
+	GetByID(attachmentID string) (*model.Attachment, error)
+	CountByPost(postID string) (int, error)
+	Create(attachment *model.Attachment, userID, storageKey string, maxPerPost int) (*model.Attachment, error)
+	Detach(attachmentID string) error
+	GetDetachedKeys(limit int) (map[string]string, error)
+	DeleteDetached(attachmentIDs []string) error
+}
+
+type AttachmentService interface {
+	GetAttachmentByID(attachmentID string) (*model.Attachment, error)
+	UploadAttachment(postID, userID, filename, contentType string, size int64, body io.Reader) (*model.Attachment, error)
+	DeleteAttachment(attachmentID string) error
+	CleanupDetachedAttachments() error
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"github.com/arshamroshannejad/task-rootext/internal/entities"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+)
+
+type CommentRepository interface {
+	GetAll(postID string, filter *helpers.PaginateFilter) (*[]model.Comment, helpers.Metadata, error)
+	GetReplies(parentIDs []string, filter *helpers.PaginateFilter) (*[]model.Comment, error)
+	GetByID(commentID string) (*model.Comment, error)
+	Create(comment *entities.CommentCreateRequest, postID, userID string) (*model.Comment, error)
+	Update(comment *entities.CommentUpdateRequest, commentID string) (*model.Comment, error)
+	Delete(commentID string) error
+	AddVote(commentID, userID, vote string) error
+	RemoveVote(commentID, userID string) error
+}
+
+type CommentService interface {
+	GetAllComments(postID string, filter *helpers.PaginateFilter) (*[]model.Comment, helpers.Metadata, error)
+	GetCommentByID(commentID string) (*model.Comment, error)
+	CreateComment(comment *entities.CommentCreateRequest, postID, userID string) (*model.Comment, error)
+	UpdateComment(comment *entities.CommentUpdateRequest, commentID string) (*model.Comment, error)
+	DeleteComment(commentID string) error
+	AddCommentVote(commentID, userID, vote string) error
+	RemoveCommentVote(commentID, userID string) error
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"github.com/arshamroshannejad/task-rootext/internal/entities"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+)
+
+type CommunityRepository interface {
+	GetAll(filter *helpers.PaginateFilter) (*[]model.Community, helpers.Metadata, error)
+	GetByID(communityID string) (*model.Community, error)
+	GetByName(name string) (*model.Community, error)
+	Create(community *entities.CommunityCreateRequest, userID string) (*model.Community, error)
+	Update(community *entities.CommunityUpdateRequest, communityID string) (*model.Community, error)
+	IsModerator(communityID, userID string) (bool, error)
+	AddModerator(communityID, userID string) error
+	RemoveModerator(communityID, userID string) error
+}
+
+type CommunityService interface {
+	GetAllCommunities(filter *helpers.PaginateFilter) (*[]model.Community, helpers.Metadata, error)
+	GetCommunityByID(communityID string) (*model.Community, error)
+	GetCommunityByName(name string) (*model.Community, error)
+	CreateCommunity(community *entities.CommunityCreateRequest, userID string) (*model.Community, error)
+	UpdateCommunity(community *entities.CommunityUpdateRequest, communityID string) (*model.Community, error)
+	IsCommunityModerator(communityID, userID string) (bool, error)
+	AddCommunityModerator(communityID, userID string) error
+	RemoveCommunityModerator(communityID, userID string) error
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "errors"
+
+var (
+	ErrTOTPAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnabled      = errors.New("two-factor authentication is not enabled")
+	ErrTOTPNotEnrolling    = errors.New("two-factor enrollment was not started")
+	ErrTOTPCodeInvalid     = errors.New("two-factor code is invalid")
+	ErrMFATokenInvalid     = errors.New("mfa token is invalid or expired")
+	ErrMFAAttemptsExceeded = errors.New("too many invalid two-factor codes, try again later")
+)
+
+type MFARepository interface {
+	SetPendingTOTPSecret(userID, secret string) error
+	EnableTOTP(userID string, codeHashes []string) error
+	DisableTOTP(userID string) error
+	ReplaceRecoveryCodes(userID string, codeHashes []string) error
+	UseRecoveryCode(userID, codeHash string) error
+}
@@ -4,26 +4,59 @@ import (
 	"github.com/arshamroshannejad/task-rootext/internal/entities"
 	"github.com/arshamroshannejad/task-rootext/internal/helpers"
 	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"time"
 )
 
 type PostRepository interface {
-	GetAll(filter *helpers.PaginateFilter) (*[]model.Post, helpers.Metadata, error)
+	GetAll(filter *helpers.PaginateFilter, postFilter *entities.PostFilter) (*[]model.Post, helpers.Metadata, error)
 	GetByID(postID string) (*model.Post, error)
+	GetDeletedByID(postID string, window time.Duration) (*model.Post, error)
 	GetByTitle(title string) (*model.Post, error)
-	Create(post *entities.PostCreateUpdateRequest, userID string) (*model.Post, error)
+	Search(query string, filter *helpers.PaginateFilter, postFilter *entities.PostFilter) (*[]model.PostSearchResult, helpers.Metadata, error)
+	GetDraftsByUser(userID string, filter *helpers.PaginateFilter) (*[]model.Post, helpers.Metadata, error)
+	GetSavedByUser(userID string, filter *helpers.PaginateFilter) (*[]model.Post, helpers.Metadata, error)
+	GetViewerStates(userID string, postIDs []string) (map[string]model.PostViewerState, error)
+	Create(post *entities.PostCreateRequest, userID string) (*model.Post, error)
 	Update(post *entities.PostCreateUpdateRequest, postID string) (*model.Post, error)
+	Publish(postID string, publishAt *time.Time) error
+	PublishScheduled() (int64, error)
+	GetRevisions(postID string) (*[]model.PostRevision, error)
+	GetRevision(postID string, revision int) (*model.PostRevision, error)
 	Delete(postID string) error
+	Restore(postID string, window time.Duration) error
+	Purge(window time.Duration) (int64, error)
 	AddVote(postID, userID, vote string) error
 	RemoveVote(postID, userID string) error
+	Save(postID, userID string) error
+	Unsave(postID, userID string) error
+	ReconcileVoteCounts() (int64, error)
+	UpdateRisingScores(window time.Duration) error
 }
 
 type PostService interface {
-	GetAllPosts(filter *helpers.PaginateFilter) (*[]model.Post, helpers.Metadata, error)
+	GetAllPosts(filter *helpers.PaginateFilter, postFilter *entities.PostFilter) (*[]model.Post, helpers.Metadata, error)
 	GetPostByID(postID string) (*model.Post, error)
+	GetPublishedPostByID(postID string) (*model.Post, error)
+	GetDeletedPostByID(postID string) (*model.Post, error)
 	GetPostByTitle(title string) (*model.Post, error)
-	CreatePost(post *entities.PostCreateUpdateRequest, userID string) (*model.Post, error)
+	SearchPosts(query string, filter *helpers.PaginateFilter, postFilter *entities.PostFilter) (*[]model.PostSearchResult, helpers.Metadata, error)
+	GetUserDrafts(userID string, filter *helpers.PaginateFilter) (*[]model.Post, helpers.Metadata, error)
+	GetUserSavedPosts(userID string, filter *helpers.PaginateFilter) (*[]model.Post, helpers.Metadata, error)
+	ApplyViewerState(userID string, posts ...*model.Post) error
+	CreatePost(post *entities.PostCreateRequest, userID string) (*model.Post, error)
 	UpdatePost(post *entities.PostCreateUpdateRequest, postID string) (*model.Post, error)
+	PublishPost(postID string, publishAt *time.Time) (*model.Post, error)
+	PublishScheduledPosts() error
+	GetPostRevisions(postID string) (*[]model.PostRevision, error)
+	GetPostRevisionDiff(postID string, revision int) (*model.PostRevisionDiff, error)
 	DeletePost(postID string) error
+	RestorePost(postID string) error
+	PurgeDeletedPosts() error
 	AddPostVote(postID, userID, vote string) error
 	RemovePostVote(postID, userID string) error
+	SavePost(postID, userID string) error
+	UnsavePost(postID, userID string) error
+	ReconcileVoteCounts() (int64, error)
+	RefreshRisingScores() error
+	RefreshTopVotedCache()
 }
@@ -0,0 +1,12 @@
+package domain
+
+import "github.com/arshamroshannejad/task-rootext/internal/model"
+
+type SessionRepository interface {
+	GetActiveByUser(userID string) (*[]model.Session, error)
+	Create(userID, jti, userAgent, ip string) error
+	Touch(jti, ip string) error
+	RevokeByID(userID, sessionID string) (string, error)
+	RevokeByJTI(jti string) error
+	RevokeAllForUser(userID string) error
+}
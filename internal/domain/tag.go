@@ -0,0 +1,14 @@
+package domain
+
+import (
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+)
+
+type TagRepository interface {
+	GetPopular(filter *helpers.PaginateFilter) (*[]model.Tag, helpers.Metadata, error)
+}
+
+type TagService interface {
+	GetPopularTags(filter *helpers.PaginateFilter) (*[]model.Tag, helpers.Metadata, error)
+}
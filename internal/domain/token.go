@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"errors"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"time"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+)
+
+type RefreshTokenRepository interface {
+	GetByHash(tokenHash string) (*model.RefreshToken, error)
+	Create(userID, familyID, tokenHash string, expiresAt time.Time) error
+	Rotate(tokenID string, tokenHash string, expiresAt time.Time) error
+	RevokeFamily(familyID string) error
+	RevokeAllForUser(userID string) error
+}
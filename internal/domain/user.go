@@ -1,22 +1,84 @@
 package domain
 
 import (
+	"errors"
 	"github.com/arshamroshannejad/task-rootext/internal/entities"
 	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
 )
 
+var (
+	ErrVerificationTokenInvalid  = errors.New("verification token is invalid or expired")
+	ErrEmailAlreadyVerified      = errors.New("email is already verified")
+	ErrVerificationCooldown      = errors.New("a verification email was sent recently, try again later")
+	ErrPasswordResetTokenInvalid = errors.New("password reset token is invalid or expired")
+	ErrPasswordIncorrect         = errors.New("old password is incorrect")
+	ErrInvalidCredentials        = errors.New("invalid credentials")
+	ErrPasswordInvalid           = errors.New("password is incorrect")
+)
+
+// LoginThrottledError rejects a login attempt that came too soon after failed
+// ones, or while the account or the address is locked out.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return "too many failed login attempts, try again later"
+}
+
 type UserRepository interface {
 	GetByID(id string) (*model.User, error)
 	GetByEmail(email string) (*model.User, error)
-	Create(user *entities.UserAuthRequest) error
+	GetByUsername(username string) (*model.User, error)
+	GetProfile(username string) (*model.UserProfile, error)
+	Create(user *entities.UserRegisterRequest) (*model.User, error)
+	MarkEmailVerified(userID, email string) error
+	GetTokenVersion(userID string) (int, error)
+	CreatePasswordResetToken(userID, tokenHash string, expiresAt time.Time) error
+	ResetPassword(tokenHash, passwordHash string) (*model.User, error)
+	UpdatePassword(userID, passwordHash string) (*model.User, error)
+	BumpTokenVersion(userID string) (int, error)
+	UpdateRole(userID, role string) (*model.User, error)
+	UpdateProfile(userID string, profile *entities.UserProfileUpdateRequest) (*model.User, error)
 }
 
 type UserService interface {
 	GetUserByID(id string) (*model.User, error)
 	GetUserByEmail(email string) (*model.User, error)
-	CreateUser(user *entities.UserAuthRequest) error
+	GetUserByUsername(username string) (*model.User, error)
+	GetUserProfile(username string) (*model.UserProfile, error)
+	UpdateUserProfile(userID string, profile *entities.UserProfileUpdateRequest) (*model.UserProfile, error)
+	Authenticate(email, password, ip string) (*model.User, error)
+	GetLoginLockout(userID string) (*model.LoginLockout, error)
+	ClearLoginLockout(userID string) error
+	CreateUser(user *entities.UserRegisterRequest) (*model.User, error)
 	EncryptPassword(plainPass string) (string, error)
 	VerifyPassword(hashPass, plainPass string) error
-	CreateAccessToken(userID, email string) (string, error)
-	BlockJwtToken(token string, exp float64) error
+	CreateAccessToken(user *model.User, jti string) (string, error)
+	ParseAccessToken(accessToken string) (*jwt.Token, error)
+	CreateTokenPair(user *model.User, userAgent, ip string) (*model.TokenPair, error)
+	RefreshTokenPair(refreshToken, ip string) (*model.TokenPair, error)
+	GetUserSessions(userID, currentJTI string) (*[]model.Session, error)
+	RevokeSession(userID, sessionID string) error
+	Logout(jti string) error
+	IsSessionRevoked(jti string) (bool, error)
+	SendVerificationEmail(user *model.User) error
+	ResendVerificationEmail(userID string) error
+	VerifyEmail(token string) error
+	GetTokenVersion(userID string) (int, error)
+	RequestPasswordReset(email string)
+	ResetPassword(token, newPassword string) error
+	ChangePassword(userID, oldPassword, newPassword, userAgent, ip string) (*model.TokenPair, error)
+	LogoutEverywhere(userID string) error
+	SetUserRole(userID, role string) (*model.User, error)
+	BeginTOTPEnrollment(userID string) (*model.TOTPEnrollment, error)
+	ConfirmTOTPEnrollment(userID, code string) ([]string, error)
+	DisableTOTP(userID, code string) error
+	RegenerateRecoveryCodes(userID, code string) ([]string, error)
+	CreateMFAChallenge(user *model.User) (*model.MFAChallenge, error)
+	CompleteMFALogin(mfaToken, code, userAgent, ip string) (*model.TokenPair, error)
+	ExportUserData(userID string) (*model.UserExport, error)
+	DeleteAccount(userID, password, code string) error
 }
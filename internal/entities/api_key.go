@@ -0,0 +1,15 @@
+package entities
+
+import "time"
+
+type APIKeyCreateRequest struct {
+	Name   string   `json:"name" example:"nightly digest bot" validate:"required,max=64"`
+	Scopes []string `json:"scopes" example:"read,post" validate:"required,min=1,dive,oneof=read post vote"`
+	// ExpiresAt is optional, keys without it never expire.
+	ExpiresAt *time.Time `json:"expires_at" example:"2030-01-01T00:00:00Z" validate:"omitempty,gt"`
+}
+
+type APIKeyUpdateRequest struct {
+	Name   string   `json:"name" example:"nightly digest bot" validate:"required,max=64"`
+	Scopes []string `json:"scopes" example:"read" validate:"required,min=1,dive,oneof=read post vote"`
+}
@@ -0,0 +1,10 @@
+package entities
+
+type CommentCreateRequest struct {
+	Text     string  `json:"text" example:"Nice post!" validate:"required"`
+	ParentID *string `json:"parent_id" example:"4" validate:"omitempty,numeric"`
+}
+
+type CommentUpdateRequest struct {
+	Text string `json:"text" example:"Nice post! (edited)" validate:"required"`
+}
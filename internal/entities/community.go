@@ -0,0 +1,14 @@
+package entities
+
+type CommunityCreateRequest struct {
+	Name        string `json:"name" example:"golang" validate:"required,alphanum,min=3,max=64"`
+	Description string `json:"description" example:"Everything about the Go programming language."`
+}
+
+type CommunityUpdateRequest struct {
+	Description string `json:"description" example:"Everything about the Go programming language." validate:"required"`
+}
+
+type CommunityModeratorRequest struct {
+	UserID string `json:"user_id" example:"456" validate:"required,numeric"`
+}
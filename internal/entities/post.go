@@ -1,10 +1,60 @@
 package entities
 
+import (
+	"time"
+
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+)
+
 type PostCreateUpdateRequest struct {
-	Title string `json:"title" example:"My New Post" validate:"required"`
-	Text  string `json:"text" example:"Content of my new post." validate:"required"`
+	Title       string `json:"title" example:"My New Post" validate:"required"`
+	Text        string `json:"text" example:"Content of my new post." validate:"required"`
+	CommunityID string `json:"community_id" example:"1" validate:"required,numeric"`
+	// Tags replaces the tags of the post. leave it out on update to keep the current ones.
+	Tags []string `json:"tags" example:"golang,redis" validate:"omitempty,max=5,dive,required,max=32"`
+}
+
+// PostCreateRequest lets a new post start as a draft or be scheduled for later.
+// an empty status publishes the post right away.
+type PostCreateRequest struct {
+	PostCreateUpdateRequest
+	Status    string     `json:"status" example:"scheduled" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt *time.Time `json:"publish_at" example:"2030-01-01T10:00:00Z" validate:"required_if=Status scheduled"`
+}
+
+// PostPublishRequest publishes a draft right away, or schedules it when
+// publish_at is in the future.
+type PostPublishRequest struct {
+	PublishAt *time.Time `json:"publish_at" example:"2030-01-01T10:00:00Z"`
 }
 
 type VoteRequest struct {
 	Value string `json:"value" example:"-1" validate:"required,oneof=1 -1"`
 }
+
+// PostFilter narrows down the posts returned by PostRepository.GetAll.
+// zero values mean no filtering.
+type PostFilter struct {
+	CommunityID string
+	Tags        []string
+	// TagMode is "any" to match posts with at least one of Tags, or "all"
+	// to match posts that have every one of them.
+	TagMode string
+	// UserID keeps the posts written by one user.
+	UserID string
+}
+
+// PostCursorTypes maps the sort values of the post listings to the postgres
+// type of the column they order by, which cursor values must parse as.
+var PostCursorTypes = map[string]string{
+	"created_at":    helpers.CursorTimestamp,
+	"vote_count":    helpers.CursorInteger,
+	"hot":           helpers.CursorFloat,
+	"best":          helpers.CursorFloat,
+	"rising":        helpers.CursorFloat,
+	"controversial": helpers.CursorFloat,
+}
+
+func (p *PostFilter) IsEmpty() bool {
+	return p.CommunityID == "" && p.UserID == "" && len(p.Tags) == 0
+}
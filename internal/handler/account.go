@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/entities"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"net/http"
+)
+
+// ExportMyDataHandler godoc
+//
+//	@Summary		Export my data
+//	@Description	Download the profile, posts, comments and votes of the current user. format json returns a single document, zip returns an archive with profile.json, posts.json, comments.json and votes.json.
+//	@Produce		json
+//	@Produce		application/zip
+//	@Tags			Users
+//	@Security		BearerAuth
+//	@Param			format	query		string	false	"json or zip"	default(json)
+//	@Success		200		{object}	helpers.UserExport
+//	@Failure		400		{object}	helpers.BadRequest
+//	@Failure		500		{object}	helpers.InternalServerError
+//	@Router			/me/export [get]
+func (u *UserHandlerImpl) ExportMyDataHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	v := helpers.NewValidator()
+	format := v.ReadQsString(r.URL.Query(), "format", "json")
+	if v.Check(v.In(format, "json", "zip"), "format", "must be json or zip"); !v.IsValid() {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": v.Errors})
+		return
+	}
+	export, err := u.UserService.ExportUserData(userID)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	filename := fmt.Sprintf("export-%s-%s.%s", userID, export.ExportedAt.Format("20060102"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if format == "json" {
+		helpers.WriteJson(w, http.StatusOK, export)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.WriteHeader(http.StatusOK)
+	// the status is out already, a failure can only cut the archive short
+	_ = writeExportArchive(w, export)
+}
+
+// DeleteMyAccountHandler godoc
+//
+//	@Summary		Delete my account
+//	@Description	Delete the account of the current user. published posts and comments stay without any trace of the user, drafts, votes, saved posts and sessions are removed and every token stops working. the password is needed, and a two-factor or recovery code when two-factor authentication is enabled.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Users
+//	@Security		BearerAuth
+//	@Param			deleteBody	body		entities.DeleteAccountRequest	true	"current password, and code when two-factor authentication is enabled. authenticate required!"
+//	@Success		204			{object}	nil
+//	@Failure		400			{object}	helpers.PasswordInvalid
+//	@Failure		400			{object}	helpers.TOTPCodeInvalid
+//	@Failure		429			{object}	helpers.MFAAttemptsExceeded
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/me [delete]
+func (u *UserHandlerImpl) DeleteMyAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	reqBody := new(entities.DeleteAccountRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	if err := u.UserService.DeleteAccount(userID, reqBody.Password, reqBody.Code); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrPasswordInvalid):
+			helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		default:
+			writeMFAError(w, err)
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusNoContent, nil)
+}
+
+// writeExportArchive writes the export as a zip archive with one JSON file per
+// kind of data.
+func writeExportArchive(w http.ResponseWriter, export *model.UserExport) error {
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", helpers.M{"exported_at": export.ExportedAt, "profile": export.Profile}},
+		{"posts.json", export.Posts},
+		{"comments.json", export.Comments},
+		{"votes.json", helpers.M{"post_votes": export.PostVotes, "comment_votes": export.CommentVotes}},
+	}
+	for _, file := range files {
+		fileWriter, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(fileWriter)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
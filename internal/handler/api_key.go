@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/entities"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+type APIKeyHandlerImpl struct {
+	APIKeyService domain.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService domain.APIKeyService) *APIKeyHandlerImpl {
+	return &APIKeyHandlerImpl{
+		APIKeyService: apiKeyService,
+	}
+}
+
+// GetMyAPIKeysHandler godoc
+//
+//	@Summary		Get my API keys
+//	@Description	Get the API keys of the logged-in user, newest first. the keys themselves are never shown again, only their prefix. authenticate required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			API Keys
+//	@Security		BearerAuth
+//	@Success		200	{object}	helpers.AllAPIKeys
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@Router			/me/api-keys [get]
+func (a *APIKeyHandlerImpl) GetMyAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	apiKeys, err := a.APIKeyService.GetUserAPIKeys(userID)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"api_keys": apiKeys})
+}
+
+// GetMyAPIKeyHandler godoc
+//
+//	@Summary		Get an API key
+//	@Description	Get an API key of the logged-in user by ID. authenticate required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			API Keys
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"API Key ID"
+//	@Success		200	{object}	helpers.APIKey
+//	@Failure		404	{object}	helpers.APIKeyNotFound
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@Router			/me/api-keys/{id} [get]
+func (a *APIKeyHandlerImpl) GetMyAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	keyID := chi.URLParam(r, "id")
+	apiKey, err := a.APIKeyService.GetAPIKeyByID(userID, keyID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "api key not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, apiKey)
+}
+
+// CreateAPIKeyHandler godoc
+//
+//	@Summary		Create an API key
+//	@Description	Create an API key for scripts and bots. send it in the X-API-Key header or as a bearer token. the key is only shown in this response. authenticate required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			API Keys
+//	@Security		BearerAuth
+//	@Param			apiKeyBody	body		entities.APIKeyCreateRequest	true	"scopes can be read, post and vote. leave expires_at out for a key that never expires"
+//	@Success		201			{object}	helpers.CreatedAPIKey
+//	@Failure		400			{object}	helpers.BadRequest
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/me/api-keys [post]
+func (a *APIKeyHandlerImpl) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	reqBody := new(entities.APIKeyCreateRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	createdKey, err := a.APIKeyService.CreateAPIKey(userID, reqBody)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusCreated, createdKey)
+}
+
+// UpdateAPIKeyHandler godoc
+//
+//	@Summary		Update an API key
+//	@Description	Rename an API key of the logged-in user or change its scopes. authenticate required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			API Keys
+//	@Security		BearerAuth
+//	@Param			id			path		int								true	"API Key ID"
+//	@Param			apiKeyBody	body		entities.APIKeyUpdateRequest	true	"scopes can be read, post and vote"
+//	@Success		200			{object}	helpers.APIKey
+//	@Failure		400			{object}	helpers.BadRequest
+//	@Failure		404			{object}	helpers.APIKeyNotFound
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/me/api-keys/{id} [put]
+func (a *APIKeyHandlerImpl) UpdateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	keyID := chi.URLParam(r, "id")
+	reqBody := new(entities.APIKeyUpdateRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	updatedKey, err := a.APIKeyService.UpdateAPIKey(userID, keyID, reqBody)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "api key not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, updatedKey)
+}
+
+// DeleteAPIKeyHandler godoc
+//
+//	@Summary		Delete an API key
+//	@Description	Delete an API key of the logged-in user by ID, it stops working right away. authenticate required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			API Keys
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"API Key ID"
+//	@Success		204	{object}	nil
+//	@Failure		404	{object}	helpers.APIKeyNotFound
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@Router			/me/api-keys/{id} [delete]
+func (a *APIKeyHandlerImpl) DeleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	keyID := chi.URLParam(r, "id")
+	if err := a.APIKeyService.DeleteAPIKey(userID, keyID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "api key not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/arshamroshannejad/task-rootext/config"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/policy"
+	"github.com/go-chi/chi/v5"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+)
+
+// multipartOverhead leaves room for the multipart boundaries and headers
+// around the uploaded file when the request body is limited.
+const multipartOverhead = 1 << 20
+
+type AttachmentHandlerImpl struct {
+	AttachmentService domain.AttachmentService
+	PostService       domain.PostService
+	Policy            *policy.Policy
+	Cfg               *config.Config
+}
+
+func NewAttachmentHandler(attachmentService domain.AttachmentService, postService domain.PostService, accessPolicy *policy.Policy, cfg *config.Config) *AttachmentHandlerImpl {
+	return &AttachmentHandlerImpl{
+		AttachmentService: attachmentService,
+		PostService:       postService,
+		Policy:            accessPolicy,
+		Cfg:               cfg,
+	}
+}
+
+// UploadAttachmentHandler godoc
+//
+//	@Summary		Upload an attachment
+//	@Description	Attach an image or a file to a post as multipart/form-data. the author, admins and moderators can do it.
+//	@Description	the type is detected from the file content and must be one of the allowed types. the size and the number of attachments per post are limited.
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Tags			Attachments
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			id		path		int		true	"Post ID"
+//	@Param			file	formData	file	true	"file to attach. authenticated required!"
+//	@Success		201		{object}	helpers.Attachment
+//	@Failure		400		{object}	helpers.BadRequest
+//	@Failure		404		{object}	helpers.PostNotFound
+//	@Failure		403		{object}	helpers.Forbidden
+//	@Failure		409		{object}	helpers.TooManyAttachments
+//	@Failure		413		{object}	helpers.AttachmentTooLarge
+//	@Failure		415		{object}	helpers.AttachmentTypeNotAllowed
+//	@Failure		500		{object}	helpers.InternalServerError
+//	@Router			/post/{id}/attachments [post]
+func (a *AttachmentHandlerImpl) UploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	actor := policy.ActorFromRequest(r)
+	postID := chi.URLParam(r, "id")
+	post, err := a.PostService.GetPostByID(postID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "post not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	if !a.Policy.CanManageAttachments(actor, post) {
+		helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": http.StatusText(http.StatusForbidden)})
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, a.Cfg.Attachments.MaxSize+multipartOverhead)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesError):
+			helpers.WriteJson(w, http.StatusRequestEntityTooLarge, helpers.M{"error": "file is too large"})
+		default:
+			helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": "file must be provided as multipart/form-data"})
+		}
+		return
+	}
+	defer file.Close()
+	if header.Size > a.Cfg.Attachments.MaxSize {
+		helpers.WriteJson(w, http.StatusRequestEntityTooLarge, helpers.M{"error": "file is too large"})
+		return
+	}
+	if len(header.Filename) > 255 {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": "filename must be a maximum of 255 characters"})
+		return
+	}
+	// the type is sniffed from the content, the one sent by the client is not trusted
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": "file can not be read"})
+		return
+	}
+	contentType := http.DetectContentType(sniff[:n])
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !slices.Contains(a.Cfg.Attachments.AllowedTypes, mediaType) {
+		helpers.WriteJson(w, http.StatusUnsupportedMediaType, helpers.M{"error": "file type is not allowed"})
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	attachment, err := a.AttachmentService.UploadAttachment(postID, actor.UserID, header.Filename, contentType, header.Size, file)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrTooManyAttachments):
+			helpers.WriteJson(w, http.StatusConflict, helpers.M{"error": err.Error()})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusCreated, attachment)
+}
+
+// DeleteAttachmentHandler godoc
+//
+//	@Summary		Delete an attachment
+//	@Description	Remove an attachment from a post. the author, admins and moderators can do it. authenticated required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Attachments
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			id				path		int	true	"Post ID"
+//	@Param			attachmentID	path		int	true	"Attachment ID"
+//	@Success		204				{object}	nil
+//	@Failure		404				{object}	helpers.AttachmentNotFound
+//	@Failure		403				{object}	helpers.Forbidden
+//	@Failure		500				{object}	helpers.InternalServerError
+//	@Router			/post/{id}/attachments/{attachmentID} [delete]
+func (a *AttachmentHandlerImpl) DeleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	actor := policy.ActorFromRequest(r)
+	postID := chi.URLParam(r, "id")
+	attachmentID := chi.URLParam(r, "attachmentID")
+	post, err := a.PostService.GetPostByID(postID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "post not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	if !a.Policy.CanManageAttachments(actor, post) {
+		helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": http.StatusText(http.StatusForbidden)})
+		return
+	}
+	attachment, err := a.AttachmentService.GetAttachmentByID(attachmentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "attachment not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	if attachment.PostID != postID {
+		helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "attachment not found"})
+		return
+	}
+	if err := a.AttachmentService.DeleteAttachment(attachmentID); err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusNoContent, nil)
+}
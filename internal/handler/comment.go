@@ -0,0 +1,351 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/entities"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"github.com/arshamroshannejad/task-rootext/internal/policy"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+type CommentHandlerImpl struct {
+	CommentService domain.CommentService
+	PostService    domain.PostService
+	Policy         *policy.Policy
+}
+
+func NewCommentHandler(commentService domain.CommentService, postService domain.PostService, accessPolicy *policy.Policy) *CommentHandlerImpl {
+	return &CommentHandlerImpl{
+		CommentService: commentService,
+		PostService:    postService,
+		Policy:         accessPolicy,
+	}
+}
+
+// GetAllCommentsHandler godoc
+//
+//	@Summary		Get All Comments of a post
+//	@Description	this endpoint provide top level comments of a post with their nested replies. also (pagination, sort, order) is available.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Comments
+//	@Param			id	path		int							true	"Post ID"
+//	@Param			_	query		helpers.CommentQueryParams	false	"Query Params"
+//	@Success		200	{object}	helpers.AllComments
+//	@Failure		400	{object}	helpers.BadRequest
+//	@Failure		404	{object}	helpers.PostNotFound
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@router			/post/{id}/comments [get]
+func (c *CommentHandlerImpl) GetAllCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	postID := chi.URLParam(r, "id")
+	var filter helpers.PaginateFilter
+	v := helpers.NewValidator()
+	qs := r.URL.Query()
+	filter.Page = v.ReadQsInt(qs, "page", 1)
+	filter.PageSize = v.ReadQsInt(qs, "page_size", 10)
+	filter.Sort = v.ReadQsString(qs, "sort", "-vote_count")
+	filter.SortSafeList = []string{"created_at", "-created_at", "vote_count", "-vote_count"}
+	if filter.Validate(v); !v.IsValid() {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": v.Errors})
+		return
+	}
+	if _, err := c.PostService.GetPublishedPostByID(postID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "post not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	comments, metaData, err := c.CommentService.GetAllComments(postID, &filter)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"metadata": metaData, "comments": comments})
+}
+
+// CreateCommentHandler godoc
+//
+//	@Summary		Create a new comment
+//	@Description	Create a comment on a post. send parent_id to reply to another comment of the same post.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Comments
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			id			path		int								true	"Post ID"
+//	@Param			commentBody	body		entities.CommentCreateRequest	true	"just send text and optional parent_id. authenticated required!"
+//	@Success		201			{object}	helpers.Comment
+//	@Failure		400			{object}	helpers.BadRequest
+//	@Failure		404			{object}	helpers.PostNotFound
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/post/{id}/comments [post]
+func (c *CommentHandlerImpl) CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	postID := chi.URLParam(r, "id")
+	reqBody := new(entities.CommentCreateRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	if _, err := c.PostService.GetPublishedPostByID(postID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "post not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	if reqBody.ParentID != nil {
+		parent, err := c.CommentService.GetCommentByID(*reqBody.ParentID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+			return
+		}
+		if err != nil || parent.PostID != postID {
+			helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": "parent comment not found on this post"})
+			return
+		}
+	}
+	createdComment, err := c.CommentService.CreateComment(reqBody, postID, userID)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	createdComment.Replies = []model.Comment{}
+	helpers.WriteJson(w, http.StatusCreated, createdComment)
+}
+
+// UpdateCommentHandler godoc
+//
+//	@Summary		Update an existing comment
+//	@Description	Update a comment with the provided ID and data. the author, admins and moderators can edit it.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Comments
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			id			path		int								true	"Post ID"
+//	@Param			commentID	path		int								true	"Comment ID"
+//	@Param			commentBody	body		entities.CommentUpdateRequest	true	"just send text. authenticated required!"
+//	@Success		200			{object}	helpers.Comment
+//	@Failure		400			{object}	helpers.BadRequest
+//	@Failure		404			{object}	helpers.CommentNotFound
+//	@Failure		403			{object}	helpers.Forbidden
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/post/{id}/comments/{commentID} [put]
+func (c *CommentHandlerImpl) UpdateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	actor := policy.ActorFromRequest(r)
+	postID := chi.URLParam(r, "id")
+	commentID := chi.URLParam(r, "commentID")
+	reqBody := new(entities.CommentUpdateRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	if _, err := c.PostService.GetPublishedPostByID(postID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "post not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	comment, err := c.CommentService.GetCommentByID(commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "comment not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	if comment.PostID != postID {
+		helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "comment not found"})
+		return
+	}
+	if !c.Policy.CanEditComment(actor, comment) {
+		helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": http.StatusText(http.StatusForbidden)})
+		return
+	}
+	updatedComment, err := c.CommentService.UpdateComment(reqBody, commentID)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	updatedComment.Replies = []model.Comment{}
+	helpers.WriteJson(w, http.StatusOK, updatedComment)
+}
+
+// DeleteCommentHandler godoc
+//
+//	@Summary		Delete a comment
+//	@Description	Delete a comment and all of its replies by ID. the author, admins and moderators can do it. authenticated required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Comments
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			id			path		int	true	"Post ID"
+//	@Param			commentID	path		int	true	"Comment ID"
+//	@Success		204			{object}	nil
+//	@Failure		404			{object}	helpers.CommentNotFound
+//	@Failure		403			{object}	helpers.Forbidden
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/post/{id}/comments/{commentID} [delete]
+func (c *CommentHandlerImpl) DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	actor := policy.ActorFromRequest(r)
+	postID := chi.URLParam(r, "id")
+	commentID := chi.URLParam(r, "commentID")
+	if _, err := c.PostService.GetPublishedPostByID(postID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "post not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	comment, err := c.CommentService.GetCommentByID(commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "comment not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	if comment.PostID != postID {
+		helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "comment not found"})
+		return
+	}
+	if !c.Policy.CanDeleteComment(actor, comment) {
+		helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": http.StatusText(http.StatusForbidden)})
+		return
+	}
+	if err := c.CommentService.DeleteComment(commentID); err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusNoContent, nil)
+}
+
+// AddCommentVoteHandler godoc
+//
+//	@Summary		Add a vote to a comment
+//	@Description	Add a vote for a comment by ID. authenticated required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Comments
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			id			path		int						true	"Post ID"
+//	@Param			commentID	path		int						true	"Comment ID"
+//	@Param			voteBody	body		entities.VoteRequest	true	"value must 1 or -1"
+//	@Success		200			{object}	helpers.VoteSuccessful
+//	@Failure		400			{object}	helpers.BadRequest
+//	@Failure		404			{object}	helpers.CommentNotFound
+//	@Failure		403			{object}	helpers.Forbidden
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/post/{id}/comments/{commentID}/vote [post]
+func (c *CommentHandlerImpl) AddCommentVoteHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	postID := chi.URLParam(r, "id")
+	commentID := chi.URLParam(r, "commentID")
+	reqBody := new(entities.VoteRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	if _, err := c.PostService.GetPublishedPostByID(postID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "post not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	comment, err := c.CommentService.GetCommentByID(commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "comment not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	if comment.PostID != postID {
+		helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "comment not found"})
+		return
+	}
+	if !c.Policy.CanVoteComment(policy.ActorFromRequest(r), comment) {
+		helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": http.StatusText(http.StatusForbidden)})
+		return
+	}
+	if err := c.CommentService.AddCommentVote(commentID, userID, reqBody.Value); err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"response": "successful"})
+}
+
+// RemoveCommentVoteHandler godoc
+//
+//	@Summary		Remove a vote from a comment
+//	@Description	Remove a vote for a comment by ID. authenticated required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Comments
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			id			path		int	true	"Post ID"
+//	@Param			commentID	path		int	true	"Comment ID"
+//	@Success		204			{object}	nil
+//	@Failure		404			{object}	helpers.CommentNotFound
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/post/{id}/comments/{commentID}/unvote [delete]
+func (c *CommentHandlerImpl) RemoveCommentVoteHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	postID := chi.URLParam(r, "id")
+	commentID := chi.URLParam(r, "commentID")
+	if _, err := c.PostService.GetPublishedPostByID(postID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "post not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	comment, err := c.CommentService.GetCommentByID(commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "comment not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	if comment.PostID != postID {
+		helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "comment not found"})
+		return
+	}
+	if err := c.CommentService.RemoveCommentVote(commentID, userID); err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/entities"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/policy"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+type CommunityHandlerImpl struct {
+	CommunityService domain.CommunityService
+	UserService      domain.UserService
+	Policy           *policy.Policy
+}
+
+func NewCommunityHandler(communityService domain.CommunityService, userService domain.UserService, accessPolicy *policy.Policy) *CommunityHandlerImpl {
+	return &CommunityHandlerImpl{
+		CommunityService: communityService,
+		UserService:      userService,
+		Policy:           accessPolicy,
+	}
+}
+
+// GetAllCommunitiesHandler godoc
+//
+//	@Summary		Get All Communities
+//	@Description	this endpoint provide all communities. also (pagination, sort, order) is available.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Communities
+//	@Param			_	query		helpers.CommunityQueryParams	false	"Query Params"
+//	@Success		200	{object}	helpers.AllCommunities
+//	@Failure		400	{object}	helpers.BadRequest
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@router			/community [get]
+func (c *CommunityHandlerImpl) GetAllCommunitiesHandler(w http.ResponseWriter, r *http.Request) {
+	var filter helpers.PaginateFilter
+	v := helpers.NewValidator()
+	qs := r.URL.Query()
+	filter.Page = v.ReadQsInt(qs, "page", 1)
+	filter.PageSize = v.ReadQsInt(qs, "page_size", 10)
+	filter.Sort = v.ReadQsString(qs, "sort", "name")
+	filter.SortSafeList = []string{"name", "-name", "created_at", "-created_at"}
+	if filter.Validate(v); !v.IsValid() {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": v.Errors})
+		return
+	}
+	communities, metaData, err := c.CommunityService.GetAllCommunities(&filter)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"metadata": metaData, "communities": communities})
+}
+
+// GetCommunityHandler godoc
+//
+//	@Summary		Get a single community
+//	@Description	Get a single community with id, including its moderator list
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Communities
+//	@Param			id	path		int	true	"Community ID"
+//	@Success		200	{object}	helpers.Community
+//	@Failure		404	{object}	helpers.CommunityNotFound
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@router			/community/{id} [get]
+func (c *CommunityHandlerImpl) GetCommunityHandler(w http.ResponseWriter, r *http.Request) {
+	communityID := chi.URLParam(r, "id")
+	community, err := c.CommunityService.GetCommunityByID(communityID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "community not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, community)
+}
+
+// CreateCommunityHandler godoc
+//
+//	@Summary		Create a new community
+//	@Description	Create a new community. the creator becomes its first moderator.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Communities
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			communityBody	body		entities.CommunityCreateRequest	true	"name must be alphanumeric. authenticated required!"
+//	@Success		201				{object}	helpers.Community
+//	@Failure		400				{object}	helpers.BadRequest
+//	@Failure		409				{object}	helpers.CommunityExists
+//	@Failure		500				{object}	helpers.InternalServerError
+//	@Router			/community [post]
+func (c *CommunityHandlerImpl) CreateCommunityHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	reqBody := new(entities.CommunityCreateRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	if _, err := c.CommunityService.GetCommunityByName(reqBody.Name); !errors.Is(err, sql.ErrNoRows) {
+		helpers.WriteJson(w, http.StatusConflict, helpers.M{"error": "community already exists"})
+		return
+	}
+	createdCommunity, err := c.CommunityService.CreateCommunity(reqBody, userID)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusCreated, createdCommunity)
+}
+
+// UpdateCommunityHandler godoc
+//
+//	@Summary		Update an existing community
+//	@Description	Update a community description. moderators of the community, admins and moderators can do it.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Communities
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			id				path		int								true	"Community ID"
+//	@Param			communityBody	body		entities.CommunityUpdateRequest	true	"just send description. authenticated required!"
+//	@Success		200				{object}	helpers.Community
+//	@Failure		400				{object}	helpers.BadRequest
+//	@Failure		404				{object}	helpers.CommunityNotFound
+//	@Failure		403				{object}	helpers.Forbidden
+//	@Failure		500				{object}	helpers.InternalServerError
+//	@Router			/community/{id} [put]
+func (c *CommunityHandlerImpl) UpdateCommunityHandler(w http.ResponseWriter, r *http.Request) {
+	actor := policy.ActorFromRequest(r)
+	communityID := chi.URLParam(r, "id")
+	reqBody := new(entities.CommunityUpdateRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	if _, err := c.CommunityService.GetCommunityByID(communityID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "community not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	allowed, err := c.Policy.CanModerateCommunity(actor, communityID)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	if !allowed {
+		helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": http.StatusText(http.StatusForbidden)})
+		return
+	}
+	updatedCommunity, err := c.CommunityService.UpdateCommunity(reqBody, communityID)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, updatedCommunity)
+}
+
+// AddCommunityModeratorHandler godoc
+//
+//	@Summary		Add a moderator to a community
+//	@Description	Add a user to the moderator list of a community. the creator of the community and admins can do it.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Communities
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			id				path		int									true	"Community ID"
+//	@Param			moderatorBody	body		entities.CommunityModeratorRequest	true	"just send user_id. authenticated required!"
+//	@Success		200				{object}	helpers.Community
+//	@Failure		400				{object}	helpers.BadRequest
+//	@Failure		404				{object}	helpers.CommunityNotFound
+//	@Failure		403				{object}	helpers.Forbidden
+//	@Failure		500				{object}	helpers.InternalServerError
+//	@Router			/community/{id}/moderators [post]
+func (c *CommunityHandlerImpl) AddCommunityModeratorHandler(w http.ResponseWriter, r *http.Request) {
+	actor := policy.ActorFromRequest(r)
+	communityID := chi.URLParam(r, "id")
+	reqBody := new(entities.CommunityModeratorRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	community, err := c.CommunityService.GetCommunityByID(communityID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "community not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	if !c.Policy.CanManageModerators(actor, community) {
+		helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": http.StatusText(http.StatusForbidden)})
+		return
+	}
+	if _, err := c.UserService.GetUserByID(reqBody.UserID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "user not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	if err := c.CommunityService.AddCommunityModerator(communityID, reqBody.UserID); err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	updatedCommunity, err := c.CommunityService.GetCommunityByID(communityID)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, updatedCommunity)
+}
+
+// RemoveCommunityModeratorHandler godoc
+//
+//	@Summary		Remove a moderator from a community
+//	@Description	Remove a user from the moderator list of a community. the creator and admins can do it and the creator can not be removed.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Communities
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			id		path		int	true	"Community ID"
+//	@Param			userID	path		int	true	"Moderator User ID"
+//	@Success		204		{object}	nil
+//	@Failure		400		{object}	helpers.BadRequest
+//	@Failure		404		{object}	helpers.CommunityNotFound
+//	@Failure		403		{object}	helpers.Forbidden
+//	@Failure		500		{object}	helpers.InternalServerError
+//	@Router			/community/{id}/moderators/{userID} [delete]
+func (c *CommunityHandlerImpl) RemoveCommunityModeratorHandler(w http.ResponseWriter, r *http.Request) {
+	actor := policy.ActorFromRequest(r)
+	communityID := chi.URLParam(r, "id")
+	moderatorID := chi.URLParam(r, "userID")
+	community, err := c.CommunityService.GetCommunityByID(communityID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "community not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	if !c.Policy.CanManageModerators(actor, community) {
+		helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": http.StatusText(http.StatusForbidden)})
+		return
+	}
+	if moderatorID == community.CreatorID {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": "creator can not be removed from moderators"})
+		return
+	}
+	if err := c.CommunityService.RemoveCommunityModerator(communityID, moderatorID); err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/keyring"
+	"net/http"
+)
+
+type JWKSHandlerImpl struct {
+	Keyring *keyring.Keyring
+}
+
+func NewJWKSHandler(keys *keyring.Keyring) *JWKSHandlerImpl {
+	return &JWKSHandlerImpl{
+		Keyring: keys,
+	}
+}
+
+// JWKSHandler serves the public keys access tokens are signed with, so other
+// services can verify them. it lives outside the API base path where clients
+// look for it, at /.well-known/jwks.json.
+func (j *JWKSHandlerImpl) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	// verifiers may cache the keys for a while, a new key is published
+	// before it signs anything so caches pick it up in time
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	helpers.WriteJson(w, http.StatusOK, j.Keyring.JWKS())
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"errors"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/entities"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"net/http"
+)
+
+// LoginMFAHandler godoc
+//
+//	@Summary		Login with a two-factor code
+//	@Description	Second login step for users with two-factor authentication. exchange the mfa_token of /auth/login and a TOTP code, or a recovery code, for an access token and a refresh token
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Auth
+//	@Param			mfaLoginRequest	body		entities.MFALoginRequest	true	"mfa_token from login and a code from the authenticator app or a recovery code"
+//	@Success		200				{object}	helpers.LoginOk
+//	@Failure		400				{object}	helpers.BadRequest
+//	@Failure		401				{object}	helpers.TOTPCodeInvalid
+//	@Failure		429				{object}	helpers.MFAAttemptsExceeded
+//	@Failure		500				{object}	helpers.InternalServerError
+//	@Router			/auth/login/mfa [post]
+func (u *UserHandlerImpl) LoginMFAHandler(w http.ResponseWriter, r *http.Request) {
+	reqBody := new(entities.MFALoginRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	tokens, err := u.UserService.CompleteMFALogin(reqBody.MFAToken, reqBody.Code, r.UserAgent(), helpers.ClientIP(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrMFATokenInvalid), errors.Is(err, domain.ErrTOTPCodeInvalid):
+			helpers.WriteJson(w, http.StatusUnauthorized, helpers.M{"error": err.Error()})
+		case errors.Is(err, domain.ErrMFAAttemptsExceeded):
+			helpers.WriteJson(w, http.StatusTooManyRequests, helpers.M{"error": err.Error()})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, tokens)
+}
+
+// EnrollTOTPHandler godoc
+//
+//	@Summary		Start two-factor enrollment
+//	@Description	Create a TOTP secret for the logged-in user. show the provisioning uri as a QR code to the authenticator app, then confirm it with a code. starting again replaces an unconfirmed secret. authenticate required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Auth
+//	@Security		BearerAuth
+//	@Success		200	{object}	helpers.TOTPEnrollment
+//	@Failure		409	{object}	helpers.TOTPAlreadyEnabled
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@Router			/me/mfa/totp [post]
+func (u *UserHandlerImpl) EnrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	enrollment, err := u.UserService.BeginTOTPEnrollment(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrTOTPAlreadyEnabled):
+			helpers.WriteJson(w, http.StatusConflict, helpers.M{"error": err.Error()})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, enrollment)
+}
+
+// ConfirmTOTPHandler godoc
+//
+//	@Summary		Confirm two-factor enrollment
+//	@Description	Enable two-factor authentication with a code from the authenticator app. returns the recovery codes, which are only shown this once. authenticate required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Auth
+//	@Security		BearerAuth
+//	@Param			codeRequest	body		entities.TOTPCodeRequest	true	"code from the authenticator app"
+//	@Success		200			{object}	helpers.RecoveryCodes
+//	@Failure		400			{object}	helpers.TOTPCodeInvalid
+//	@Failure		409			{object}	helpers.TOTPAlreadyEnabled
+//	@Failure		429			{object}	helpers.MFAAttemptsExceeded
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/me/mfa/totp/confirm [post]
+func (u *UserHandlerImpl) ConfirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	reqBody := new(entities.TOTPCodeRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	codes, err := u.UserService.ConfirmTOTPEnrollment(userID, reqBody.Code)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"recovery_codes": codes})
+}
+
+// DisableTOTPHandler godoc
+//
+//	@Summary		Disable two-factor authentication
+//	@Description	Turn two-factor authentication off with a code from the authenticator app or a recovery code. authenticate required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Auth
+//	@Security		BearerAuth
+//	@Param			codeRequest	body		entities.TOTPCodeRequest	true	"code from the authenticator app or a recovery code"
+//	@Success		204			{object}	nil
+//	@Failure		400			{object}	helpers.TOTPCodeInvalid
+//	@Failure		409			{object}	helpers.TOTPNotEnabled
+//	@Failure		429			{object}	helpers.MFAAttemptsExceeded
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/me/mfa/totp [delete]
+func (u *UserHandlerImpl) DisableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	reqBody := new(entities.TOTPCodeRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	if err := u.UserService.DisableTOTP(userID, reqBody.Code); err != nil {
+		writeMFAError(w, err)
+		return
+	}
+	helpers.WriteJson(w, http.StatusNoContent, nil)
+}
+
+// RegenerateRecoveryCodesHandler godoc
+//
+//	@Summary		Regenerate recovery codes
+//	@Description	Replace every recovery code of the logged-in user with new ones. authenticate required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Auth
+//	@Security		BearerAuth
+//	@Param			codeRequest	body		entities.TOTPCodeRequest	true	"code from the authenticator app or a recovery code"
+//	@Success		200			{object}	helpers.RecoveryCodes
+//	@Failure		400			{object}	helpers.TOTPCodeInvalid
+//	@Failure		409			{object}	helpers.TOTPNotEnabled
+//	@Failure		429			{object}	helpers.MFAAttemptsExceeded
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/me/mfa/recovery-codes [post]
+func (u *UserHandlerImpl) RegenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	reqBody := new(entities.TOTPCodeRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	codes, err := u.UserService.RegenerateRecoveryCodes(userID, reqBody.Code)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"recovery_codes": codes})
+}
+
+// writeMFAError maps the errors of the two-factor settings endpoints.
+func writeMFAError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrTOTPCodeInvalid):
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+	case errors.Is(err, domain.ErrTOTPAlreadyEnabled), errors.Is(err, domain.ErrTOTPNotEnabled), errors.Is(err, domain.ErrTOTPNotEnrolling):
+		helpers.WriteJson(w, http.StatusConflict, helpers.M{"error": err.Error()})
+	case errors.Is(err, domain.ErrMFAAttemptsExceeded):
+		helpers.WriteJson(w, http.StatusTooManyRequests, helpers.M{"error": err.Error()})
+	default:
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+	}
+}
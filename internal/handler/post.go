@@ -6,27 +6,43 @@ import (
 	"github.com/arshamroshannejad/task-rootext/internal/domain"
 	"github.com/arshamroshannejad/task-rootext/internal/entities"
 	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"github.com/arshamroshannejad/task-rootext/internal/policy"
 	"github.com/go-chi/chi/v5"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type PostHandlerImpl struct {
-	PostService domain.PostService
+	PostService      domain.PostService
+	CommunityService domain.CommunityService
+	UserService      domain.UserService
+	Policy           *policy.Policy
 }
 
-func NewPostHandler(postService domain.PostService) *PostHandlerImpl {
+func NewPostHandler(postService domain.PostService, communityService domain.CommunityService, userService domain.UserService, accessPolicy *policy.Policy) *PostHandlerImpl {
 	return &PostHandlerImpl{
-		PostService: postService,
+		PostService:      postService,
+		CommunityService: communityService,
+		UserService:      userService,
+		Policy:           accessPolicy,
 	}
 }
 
 // GetAllPostsHandler godoc
 //
 //	@Summary		Get All Posts
-//	@Description	this endpoint provide all posts. also (pagination, sort, order) is available.
+//	@Description	this endpoint provide all posts. also (pagination, sort, order, community and tag filters) is available.
+//	@Description	repeat tag to filter by several tags. tag_mode any matches posts with one of them, all matches posts with every one of them.
+//	@Description	send cursor (empty for the first page) to switch from page numbers to keyset pagination with next_cursor/prev_cursor.
+//	@Description	the viewer fields (saved, my_vote, is_owner) are included when a valid token is sent.
 //	@Accept			json
 //	@Produce		json
 //	@Tags			Posts
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
 //	@Param			_	query		helpers.PostQueryParams	false	"Query Params"
 //	@Success		200	{object}	helpers.AllPosts
 //	@Success		400	{object}	helpers.BadRequest
@@ -34,31 +50,103 @@ func NewPostHandler(postService domain.PostService) *PostHandlerImpl {
 //	@router			/post [get]
 func (p *PostHandlerImpl) GetAllPostsHandler(w http.ResponseWriter, r *http.Request) {
 	var filter helpers.PaginateFilter
+	var postFilter entities.PostFilter
 	v := helpers.NewValidator()
 	qs := r.URL.Query()
 	filter.Page = v.ReadQsInt(qs, "page", 1)
 	filter.PageSize = v.ReadQsInt(qs, "page_size", 5)
 	filter.Sort = v.ReadQsString(qs, "sort", "-vote_count")
-	filter.SortSafeList = []string{"created_at", "-created_at", "vote_count", "-vote_count"}
+	filter.SortSafeList = []string{
+		"created_at", "-created_at", "vote_count", "-vote_count",
+		"hot", "-hot", "best", "-best", "rising", "-rising", "controversial", "-controversial",
+	}
+	filter.Cursor, filter.UseCursor = v.ReadQsCursor(qs, "cursor", filter.Sort, entities.PostCursorTypes)
+	if communityID := v.ReadQsInt(qs, "community_id", 0); communityID != 0 {
+		postFilter.CommunityID = strconv.Itoa(communityID)
+	}
+	postFilter.Tags = helpers.NormalizeTags(qs["tag"])
+	postFilter.TagMode = v.ReadQsString(qs, "tag_mode", "any")
+	v.Check(len(postFilter.Tags) <= 10, "tag", "must be a maximum of 10 tags")
+	v.Check(v.In(postFilter.TagMode, "any", "all"), "tag_mode", "must be any or all")
 	if filter.Validate(v); !v.IsValid() {
 		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": v.Errors})
 		return
 	}
-	posts, metaData, err := p.PostService.GetAllPosts(&filter)
+	posts, metaData, err := p.PostService.GetAllPosts(&filter, &postFilter)
 	if err != nil {
 		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
 		return
 	}
+	if err := p.applyViewerState(r, postRefs(*posts)...); err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
 	helpers.WriteJson(w, http.StatusOK, helpers.M{"metadata": metaData, "posts": posts})
 }
 
+// SearchPostsHandler godoc
+//
+//	@Summary		Search Posts
+//	@Description	full-text search over post titles and bodies. results are ranked and contain highlighted snippets. title_highlight and snippet are html escaped, the only html in them are the <mark> tags around the matches, so they can be rendered as html. also (pagination, sort, community and tag filters) is available.
+//	@Description	the viewer fields (saved, my_vote, is_owner) are included when a valid token is sent.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Posts
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			_	query		helpers.PostSearchQueryParams	false	"Query Params"
+//	@Success		200	{object}	helpers.PostSearchResults
+//	@Failure		400	{object}	helpers.BadRequest
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@router			/post/search [get]
+func (p *PostHandlerImpl) SearchPostsHandler(w http.ResponseWriter, r *http.Request) {
+	var filter helpers.PaginateFilter
+	var postFilter entities.PostFilter
+	v := helpers.NewValidator()
+	qs := r.URL.Query()
+	query := strings.TrimSpace(qs.Get("q"))
+	v.Check(query != "", "q", "must be provided")
+	v.Check(len(query) <= 256, "q", "must be a maximum of 256 characters")
+	filter.Page = v.ReadQsInt(qs, "page", 1)
+	filter.PageSize = v.ReadQsInt(qs, "page_size", 5)
+	filter.Sort = v.ReadQsString(qs, "sort", "-rank")
+	filter.SortSafeList = []string{"rank", "-rank", "created_at", "-created_at", "vote_count", "-vote_count"}
+	if communityID := v.ReadQsInt(qs, "community_id", 0); communityID != 0 {
+		postFilter.CommunityID = strconv.Itoa(communityID)
+	}
+	postFilter.Tags = helpers.NormalizeTags(qs["tag"])
+	postFilter.TagMode = v.ReadQsString(qs, "tag_mode", "any")
+	v.Check(len(postFilter.Tags) <= 10, "tag", "must be a maximum of 10 tags")
+	v.Check(v.In(postFilter.TagMode, "any", "all"), "tag_mode", "must be any or all")
+	if filter.Validate(v); !v.IsValid() {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": v.Errors})
+		return
+	}
+	results, metaData, err := p.PostService.SearchPosts(query, &filter, &postFilter)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	resultPosts := make([]*model.Post, len(*results))
+	for i := range *results {
+		resultPosts[i] = &(*results)[i].Post
+	}
+	if err := p.applyViewerState(r, resultPosts...); err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"metadata": metaData, "posts": results})
+}
+
 // GetPostHandler  godoc
 //
 //	@Summary		Get a single post
-//	@Description	Get a sing post with id
+//	@Description	Get a sing post with id. the viewer fields (saved, my_vote, is_owner) are included when a valid token is sent.
 //	@Accept			json
 //	@Produce		json
 //	@Tags			Posts
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
 //	@Param			id	path		int	true	"Post ID"
 //	@Success		200	{object}	helpers.Post
 //	@Failure		404	{object}	helpers.PostNotFound
@@ -66,7 +154,7 @@ func (p *PostHandlerImpl) GetAllPostsHandler(w http.ResponseWriter, r *http.Requ
 //	@router			/post/{id} [get]
 func (p *PostHandlerImpl) GetPostHandler(w http.ResponseWriter, r *http.Request) {
 	postID := chi.URLParam(r, "id")
-	post, err := p.PostService.GetPostByID(postID)
+	post, err := p.PostService.GetPublishedPostByID(postID)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -76,29 +164,123 @@ func (p *PostHandlerImpl) GetPostHandler(w http.ResponseWriter, r *http.Request)
 		}
 		return
 	}
+	if err := p.applyViewerState(r, post); err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
 	helpers.WriteJson(w, http.StatusOK, post)
 }
 
+// GetPostRevisionsHandler godoc
+//
+//	@Summary		Get revisions of a post
+//	@Description	Get every stored revision of a post, newest first. the history starts with the content the post was published with, edits of drafts are not recorded
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Posts
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		200	{object}	helpers.AllPostRevisions
+//	@Failure		404	{object}	helpers.PostNotFound
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@router			/post/{id}/revisions [get]
+func (p *PostHandlerImpl) GetPostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	postID := chi.URLParam(r, "id")
+	if _, err := p.PostService.GetPublishedPostByID(postID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "post not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	revisions, err := p.PostService.GetPostRevisions(postID)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"revisions": revisions})
+}
+
+// GetPostRevisionHandler godoc
+//
+//	@Summary		Get a single revision of a post
+//	@Description	Get a revision of a post with a line diff of its title and text against the previous revision
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Posts
+//	@Param			id	path		int	true	"Post ID"
+//	@Param			rev	path		int	true	"Revision number"
+//	@Success		200	{object}	helpers.PostRevisionDiff
+//	@Failure		400	{object}	helpers.BadRequest
+//	@Failure		404	{object}	helpers.RevisionNotFound
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@router			/post/{id}/revisions/{rev} [get]
+func (p *PostHandlerImpl) GetPostRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	postID := chi.URLParam(r, "id")
+	revision, err := strconv.Atoi(chi.URLParam(r, "rev"))
+	if err != nil || revision < 1 {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": "revision must be a positive integer"})
+		return
+	}
+	if _, err := p.PostService.GetPublishedPostByID(postID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "post not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	revisionDiff, err := p.PostService.GetPostRevisionDiff(postID, revision)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "revision not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, revisionDiff)
+}
+
 // CreatePostHandler godoc
 //
 //	@Summary		Create a new post
-//	@Description	Create a new post with the provided data
+//	@Description	Create a new post with the provided data. status draft keeps it private, status scheduled publishes it at publish_at.
 //	@Accept			json
 //	@Produce		json
 //	@Tags			Posts
 //	@Security		BearerAuth
-//	@Param			postBody	body		entities.PostCreateUpdateRequest	true	"just send title and text. authenticated required!"
+//	@Security		APIKeyAuth
+//	@Param			postBody	body		entities.PostCreateRequest	true	"send title, text, community_id and optionally status and publish_at. authenticated required!"
 //	@Success		201			{object}	helpers.Post
 //	@Failure		400			{object}	helpers.BadRequest
+//	@Failure		403			{object}	helpers.EmailNotVerified
+//	@Failure		404			{object}	helpers.CommunityNotFound
 //	@Failure		500			{object}	helpers.InternalServerError
 //	@Router			/post [post]
 func (p *PostHandlerImpl) CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 	userID := helpers.GetUserID(r)
-	reqBody := new(entities.PostCreateUpdateRequest)
+	reqBody := new(entities.PostCreateRequest)
 	if err := helpers.ReadJson(r, reqBody); err != nil {
 		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
 		return
 	}
+	if reqBody.Status == model.PostStatusScheduled && !reqBody.PublishAt.After(time.Now()) {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": "publish_at must be in the future"})
+		return
+	}
+	if _, err := p.CommunityService.GetCommunityByID(reqBody.CommunityID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "community not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
 	createdPost, err := p.PostService.CreatePost(reqBody, userID)
 	if err != nil {
 		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
@@ -110,13 +292,14 @@ func (p *PostHandlerImpl) CreatePostHandler(w http.ResponseWriter, r *http.Reque
 // UpdatePostHandler godoc
 //
 //	@Summary		Update an existing post
-//	@Description	Update a post with the provided ID and data
+//	@Description	Update a post with the provided ID and data. the author, admins and moderators can do it.
 //	@Accept			json
 //	@Produce		json
 //	@Tags			Posts
 //	@Security		BearerAuth
+//	@Security		APIKeyAuth
 //	@Param			id			path		int									true	"Post ID"
-//	@Param			postBody	body		entities.PostCreateUpdateRequest	true	"just send title and text. authenticated required!"
+//	@Param			postBody	body		entities.PostCreateUpdateRequest	true	"just send title, text and community_id. authenticated required!"
 //	@Success		200			{object}	helpers.Post
 //	@Failure		400			{object}	helpers.BadRequest
 //	@Failure		404			{object}	helpers.PostNotFound
@@ -124,7 +307,7 @@ func (p *PostHandlerImpl) CreatePostHandler(w http.ResponseWriter, r *http.Reque
 //	@Failure		500			{object}	helpers.InternalServerError
 //	@Router			/post/{id} [put]
 func (p *PostHandlerImpl) UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
-	userID := helpers.GetUserID(r)
+	actor := policy.ActorFromRequest(r)
 	postID := chi.URLParam(r, "id")
 	reqBody := new(entities.PostCreateUpdateRequest)
 	if err := helpers.ReadJson(r, reqBody); err != nil {
@@ -141,10 +324,19 @@ func (p *PostHandlerImpl) UpdatePostHandler(w http.ResponseWriter, r *http.Reque
 		}
 		return
 	}
-	if post.UserID != userID {
+	if !p.Policy.CanEditPost(actor, post) {
 		helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": http.StatusText(http.StatusForbidden)})
 		return
 	}
+	if _, err := p.CommunityService.GetCommunityByID(reqBody.CommunityID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "community not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
 	updatedPost, err := p.PostService.UpdatePost(reqBody, postID)
 	if err != nil {
 		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
@@ -153,14 +345,107 @@ func (p *PostHandlerImpl) UpdatePostHandler(w http.ResponseWriter, r *http.Reque
 	helpers.WriteJson(w, http.StatusOK, updatedPost)
 }
 
+// PublishPostHandler godoc
+//
+//	@Summary		Publish a draft
+//	@Description	Publish a draft or scheduled post right away, or (re)schedule it when publish_at is in the future. only the author can do it.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Posts
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			id			path		int							true	"Post ID"
+//	@Param			publishBody	body		entities.PostPublishRequest	true	"send an empty object to publish now. authenticated required!"
+//	@Success		200			{object}	helpers.Post
+//	@Failure		400			{object}	helpers.BadRequest
+//	@Failure		404			{object}	helpers.PostNotFound
+//	@Failure		403			{object}	helpers.Forbidden
+//	@Failure		409			{object}	helpers.PostAlreadyPublished
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/post/{id}/publish [post]
+func (p *PostHandlerImpl) PublishPostHandler(w http.ResponseWriter, r *http.Request) {
+	actor := policy.ActorFromRequest(r)
+	postID := chi.URLParam(r, "id")
+	reqBody := new(entities.PostPublishRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	post, err := p.PostService.GetPostByID(postID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "post not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	if !p.Policy.CanPublishPost(actor, post) {
+		helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": http.StatusText(http.StatusForbidden)})
+		return
+	}
+	if post.IsPublished() {
+		helpers.WriteJson(w, http.StatusConflict, helpers.M{"error": "post is already published"})
+		return
+	}
+	publishedPost, err := p.PostService.PublishPost(postID, reqBody.PublishAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusConflict, helpers.M{"error": "post is already published"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, publishedPost)
+}
+
+// GetMyDraftsHandler godoc
+//
+//	@Summary		Get my drafts
+//	@Description	this endpoint provide the drafts and scheduled posts of the current user. also (pagination, sort, order) is available.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Posts
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			_	query		helpers.DraftQueryParams	false	"Query Params"
+//	@Success		200	{object}	helpers.AllPosts
+//	@Failure		400	{object}	helpers.BadRequest
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@Router			/me/drafts [get]
+func (p *PostHandlerImpl) GetMyDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	var filter helpers.PaginateFilter
+	v := helpers.NewValidator()
+	qs := r.URL.Query()
+	filter.Page = v.ReadQsInt(qs, "page", 1)
+	filter.PageSize = v.ReadQsInt(qs, "page_size", 10)
+	filter.Sort = v.ReadQsString(qs, "sort", "-created_at")
+	filter.SortSafeList = []string{"created_at", "-created_at", "publish_at", "-publish_at"}
+	if filter.Validate(v); !v.IsValid() {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": v.Errors})
+		return
+	}
+	posts, metaData, err := p.PostService.GetUserDrafts(userID, &filter)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"metadata": metaData, "posts": posts})
+}
+
 // DeletePostHandler godoc
 //
 //	@Summary		Delete a post
-//	@Description	Delete a post by ID. authenticated required!
+//	@Description	Delete a post by ID. the author, admins, moderators or a moderator of the post community can do it. authenticated required!
 //	@Accept			json
 //	@Produce		json
 //	@Tags			Posts
 //	@Security		BearerAuth
+//	@Security		APIKeyAuth
 //	@Param			id	path		int	true	"Post ID"
 //	@Success		204	{object}	nil
 //	@Failure		404	{object}	helpers.PostNotFound
@@ -168,7 +453,7 @@ func (p *PostHandlerImpl) UpdatePostHandler(w http.ResponseWriter, r *http.Reque
 //	@Failure		500	{object}	helpers.InternalServerError
 //	@Router			/post/{id} [delete]
 func (p *PostHandlerImpl) DeletePostHandler(w http.ResponseWriter, r *http.Request) {
-	userID := helpers.GetUserID(r)
+	actor := policy.ActorFromRequest(r)
 	postID := chi.URLParam(r, "id")
 	post, err := p.PostService.GetPostByID(postID)
 	if err != nil {
@@ -180,7 +465,12 @@ func (p *PostHandlerImpl) DeletePostHandler(w http.ResponseWriter, r *http.Reque
 		}
 		return
 	}
-	if post.UserID != userID {
+	allowed, err := p.Policy.CanDeletePost(actor, post)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	if !allowed {
 		helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": http.StatusText(http.StatusForbidden)})
 		return
 	}
@@ -191,6 +481,55 @@ func (p *PostHandlerImpl) DeletePostHandler(w http.ResponseWriter, r *http.Reque
 	helpers.WriteJson(w, http.StatusNoContent, nil)
 }
 
+// RestorePostHandler godoc
+//
+//	@Summary		Restore a deleted post
+//	@Description	Restore a deleted post by ID. the author, admins and moderators can do it, only within the restore window. authenticated required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Posts
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		200	{object}	helpers.Post
+//	@Failure		404	{object}	helpers.PostNotFound
+//	@Failure		403	{object}	helpers.Forbidden
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@Router			/post/{id}/restore [post]
+func (p *PostHandlerImpl) RestorePostHandler(w http.ResponseWriter, r *http.Request) {
+	actor := policy.ActorFromRequest(r)
+	postID := chi.URLParam(r, "id")
+	post, err := p.PostService.GetDeletedPostByID(postID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "post not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	if !p.Policy.CanRestorePost(actor, post) {
+		helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": http.StatusText(http.StatusForbidden)})
+		return
+	}
+	if err := p.PostService.RestorePost(postID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "post not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	restoredPost, err := p.PostService.GetPostByID(postID)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, restoredPost)
+}
+
 // AddPostVoteHandler godoc
 //
 //	@Summary		Add a vote to a post
@@ -199,6 +538,7 @@ func (p *PostHandlerImpl) DeletePostHandler(w http.ResponseWriter, r *http.Reque
 //	@Produce		json
 //	@Tags			Posts
 //	@Security		BearerAuth
+//	@Security		APIKeyAuth
 //	@Param			id			path		int						true	"Post ID"
 //	@Param			voteBody	body		entities.VoteRequest	true	"value must 1 or -1"
 //	@Success		200			{object}	helpers.VoteSuccessful
@@ -215,7 +555,7 @@ func (p *PostHandlerImpl) AddPostVoteHandler(w http.ResponseWriter, r *http.Requ
 		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
 		return
 	}
-	post, err := p.PostService.GetPostByID(postID)
+	post, err := p.PostService.GetPublishedPostByID(postID)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -225,7 +565,7 @@ func (p *PostHandlerImpl) AddPostVoteHandler(w http.ResponseWriter, r *http.Requ
 		}
 		return
 	}
-	if post.UserID == userID {
+	if !p.Policy.CanVotePost(policy.ActorFromRequest(r), post) {
 		helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": http.StatusText(http.StatusForbidden)})
 		return
 	}
@@ -244,6 +584,7 @@ func (p *PostHandlerImpl) AddPostVoteHandler(w http.ResponseWriter, r *http.Requ
 //	@Produce		json
 //	@Tags			Posts
 //	@Security		BearerAuth
+//	@Security		APIKeyAuth
 //	@Param			id	path		int	true	"Post ID"
 //	@Success		204	{object}	nil
 //	@Failure		404	{object}	helpers.PostNotFound
@@ -252,7 +593,7 @@ func (p *PostHandlerImpl) AddPostVoteHandler(w http.ResponseWriter, r *http.Requ
 func (p *PostHandlerImpl) RemovePostVoteHandler(w http.ResponseWriter, r *http.Request) {
 	userID := helpers.GetUserID(r)
 	postID := chi.URLParam(r, "id")
-	if _, err := p.PostService.GetPostByID(postID); err != nil {
+	if _, err := p.PostService.GetPublishedPostByID(postID); err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "post not found"})
@@ -267,3 +608,174 @@ func (p *PostHandlerImpl) RemovePostVoteHandler(w http.ResponseWriter, r *http.R
 	}
 	helpers.WriteJson(w, http.StatusNoContent, nil)
 }
+
+// SavePostHandler godoc
+//
+//	@Summary		Save a post
+//	@Description	Save a post by ID to read it later. saving a post twice has no effect. authenticated required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Posts
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		200	{object}	helpers.PostSaved
+//	@Failure		404	{object}	helpers.PostNotFound
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@Router			/post/{id}/save [post]
+func (p *PostHandlerImpl) SavePostHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	postID := chi.URLParam(r, "id")
+	if _, err := p.PostService.GetPublishedPostByID(postID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "post not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	if err := p.PostService.SavePost(postID, userID); err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"response": "post saved"})
+}
+
+// UnsavePostHandler godoc
+//
+//	@Summary		Unsave a post
+//	@Description	Remove a post by ID from the saved posts. authenticated required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Posts
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		204	{object}	nil
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@Router			/post/{id}/save [delete]
+func (p *PostHandlerImpl) UnsavePostHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	postID := chi.URLParam(r, "id")
+	// the post is not looked up, so a deleted post can still be removed from the list
+	if err := p.PostService.UnsavePost(postID, userID); err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusNoContent, nil)
+}
+
+// GetMySavedPostsHandler godoc
+//
+//	@Summary		Get my saved posts
+//	@Description	this endpoint provide the posts saved by the current user, most recently saved first. also (pagination, sort, order) is available.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Posts
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			_	query		helpers.SavedPostQueryParams	false	"Query Params"
+//	@Success		200	{object}	helpers.AllPosts
+//	@Failure		400	{object}	helpers.BadRequest
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@Router			/me/saved [get]
+func (p *PostHandlerImpl) GetMySavedPostsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	var filter helpers.PaginateFilter
+	v := helpers.NewValidator()
+	qs := r.URL.Query()
+	filter.Page = v.ReadQsInt(qs, "page", 1)
+	filter.PageSize = v.ReadQsInt(qs, "page_size", 10)
+	filter.Sort = v.ReadQsString(qs, "sort", "-saved_at")
+	filter.SortSafeList = []string{"saved_at", "-saved_at", "created_at", "-created_at"}
+	if filter.Validate(v); !v.IsValid() {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": v.Errors})
+		return
+	}
+	posts, metaData, err := p.PostService.GetUserSavedPosts(userID, &filter)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	if err := p.applyViewerState(r, postRefs(*posts)...); err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"metadata": metaData, "posts": posts})
+}
+
+// GetUserPostsHandler godoc
+//
+//	@Summary		Get the posts of a user
+//	@Description	this endpoint provide the published posts of a user by username. also (pagination, sort, order) is available.
+//	@Description	send cursor (empty for the first page) to switch from page numbers to keyset pagination with next_cursor/prev_cursor.
+//	@Description	the viewer fields (saved, my_vote, is_owner) are included when a valid token is sent.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Users
+//	@Security		BearerAuth
+//	@Security		APIKeyAuth
+//	@Param			username	path		string						true	"Username"
+//	@Param			_			query		helpers.UserPostQueryParams	false	"Query Params"
+//	@Success		200			{object}	helpers.AllPosts
+//	@Failure		400			{object}	helpers.BadRequest
+//	@Failure		404			{object}	helpers.UserNotFound
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/users/{username}/posts [get]
+func (p *PostHandlerImpl) GetUserPostsHandler(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	var filter helpers.PaginateFilter
+	v := helpers.NewValidator()
+	qs := r.URL.Query()
+	filter.Page = v.ReadQsInt(qs, "page", 1)
+	filter.PageSize = v.ReadQsInt(qs, "page_size", 10)
+	filter.Sort = v.ReadQsString(qs, "sort", "-created_at")
+	filter.SortSafeList = []string{
+		"created_at", "-created_at", "vote_count", "-vote_count",
+		"hot", "-hot", "best", "-best", "rising", "-rising", "controversial", "-controversial",
+	}
+	filter.Cursor, filter.UseCursor = v.ReadQsCursor(qs, "cursor", filter.Sort, entities.PostCursorTypes)
+	if filter.Validate(v); !v.IsValid() {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": v.Errors})
+		return
+	}
+	user, err := p.UserService.GetUserByUsername(username)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "user not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	posts, metaData, err := p.PostService.GetAllPosts(&filter, &entities.PostFilter{UserID: user.ID})
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	if err := p.applyViewerState(r, postRefs(*posts)...); err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"metadata": metaData, "posts": posts})
+}
+
+// applyViewerState adds the per-user fields of the posts when the request is
+// made by a logged-in user.
+func (p *PostHandlerImpl) applyViewerState(r *http.Request, posts ...*model.Post) error {
+	viewerID := helpers.GetOptionalUserID(r)
+	if viewerID == "" {
+		return nil
+	}
+	return p.PostService.ApplyViewerState(viewerID, posts...)
+}
+
+func postRefs(posts []model.Post) []*model.Post {
+	refs := make([]*model.Post, len(posts))
+	for i := range posts {
+		refs[i] = &posts[i]
+	}
+	return refs
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"net/http"
+)
+
+type TagHandlerImpl struct {
+	TagService domain.TagService
+}
+
+func NewTagHandler(tagService domain.TagService) *TagHandlerImpl {
+	return &TagHandlerImpl{
+		TagService: tagService,
+	}
+}
+
+// GetPopularTagsHandler godoc
+//
+//	@Summary		Get Popular Tags
+//	@Description	this endpoint provide the tags of published posts with their usage counts, most used first. also (pagination, sort) is available.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Tags
+//	@Param			_	query		helpers.TagQueryParams	false	"Query Params"
+//	@Success		200	{object}	helpers.AllTags
+//	@Failure		400	{object}	helpers.BadRequest
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@router			/tags [get]
+func (t *TagHandlerImpl) GetPopularTagsHandler(w http.ResponseWriter, r *http.Request) {
+	var filter helpers.PaginateFilter
+	v := helpers.NewValidator()
+	qs := r.URL.Query()
+	filter.Page = v.ReadQsInt(qs, "page", 1)
+	filter.PageSize = v.ReadQsInt(qs, "page_size", 20)
+	filter.Sort = v.ReadQsString(qs, "sort", "-post_count")
+	filter.SortSafeList = []string{"post_count", "-post_count", "name", "-name"}
+	if filter.Validate(v); !v.IsValid() {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": v.Errors})
+		return
+	}
+	tags, metaData, err := t.TagService.GetPopularTags(&filter)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"metadata": metaData, "tags": tags})
+}
@@ -6,7 +6,10 @@ import (
 	"github.com/arshamroshannejad/task-rootext/internal/domain"
 	"github.com/arshamroshannejad/task-rootext/internal/entities"
 	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/go-chi/chi/v5"
+	"math"
 	"net/http"
+	"strconv"
 )
 
 type UserHandlerImpl struct {
@@ -22,18 +25,19 @@ func NewUserHandler(userService domain.UserService) *UserHandlerImpl {
 // RegisterHandler godoc
 //
 //	@Summary		Register
-//	@Description	Register a new user
+//	@Description	Register a new user. a link to verify the email address is sent to it
 //	@Accept			json
 //	@Produce		json
 //	@Tags			Auth
-//	@Param			registerRequest	body		entities.UserAuthRequest	true	"make sure send a valid email and password must be grater than 8 character"
+//	@Param			registerRequest	body		entities.UserRegisterRequest	true	"make sure send a valid email, an alphanumeric username of 3 to 32 characters and password must be grater than 8 character"
 //	@Success		201				{object}	helpers.UserCreated
 //	@Failure		400				{object}	helpers.BadRequest
 //	@Failure		409				{object}	helpers.UserExists
+//	@Failure		409				{object}	helpers.UsernameTaken
 //	@Failure		500				{object}	helpers.InternalServerError
 //	@Router			/auth/register [post]
 func (u *UserHandlerImpl) RegisterHandler(w http.ResponseWriter, r *http.Request) {
-	reqBody := new(entities.UserAuthRequest)
+	reqBody := new(entities.UserRegisterRequest)
 	if err := helpers.ReadJson(r, reqBody); err != nil {
 		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
 		return
@@ -42,30 +46,102 @@ func (u *UserHandlerImpl) RegisterHandler(w http.ResponseWriter, r *http.Request
 		helpers.WriteJson(w, http.StatusConflict, helpers.M{"error": "user already exists"})
 		return
 	}
+	if _, err := u.UserService.GetUserByUsername(reqBody.Username); !errors.Is(err, sql.ErrNoRows) {
+		helpers.WriteJson(w, http.StatusConflict, helpers.M{"error": "username is already taken"})
+		return
+	}
 	hashPassword, err := u.UserService.EncryptPassword(reqBody.Password)
 	if err != nil {
 		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
 		return
 	}
 	reqBody.Password = hashPassword
-	if err := u.UserService.CreateUser(reqBody); err != nil {
+	user, err := u.UserService.CreateUser(reqBody)
+	if err != nil {
 		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
 		return
 	}
+	// the account exists either way, a lost email can be sent again
+	_ = u.UserService.SendVerificationEmail(user)
 	helpers.WriteJson(w, http.StatusCreated, helpers.M{"response": "user created"})
 }
 
+// VerifyEmailHandler godoc
+//
+//	@Summary		Verify email
+//	@Description	Verify the email address of an account with the token of the link sent to it. every token can only be used once
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Auth
+//	@Param			verifyRequest	body		entities.VerifyEmailRequest	true	"token from the verification link"
+//	@Success		200				{object}	helpers.EmailVerified
+//	@Failure		400				{object}	helpers.VerificationTokenInvalid
+//	@Failure		409				{object}	helpers.EmailAlreadyVerified
+//	@Failure		500				{object}	helpers.InternalServerError
+//	@Router			/auth/verify-email [post]
+func (u *UserHandlerImpl) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	reqBody := new(entities.VerifyEmailRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	if err := u.UserService.VerifyEmail(reqBody.Token); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrVerificationTokenInvalid):
+			helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		case errors.Is(err, domain.ErrEmailAlreadyVerified):
+			helpers.WriteJson(w, http.StatusConflict, helpers.M{"error": err.Error()})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"response": "email verified"})
+}
+
+// ResendVerificationHandler godoc
+//
+//	@Summary		Resend verification email
+//	@Description	Send a new verification link to the email address of the logged-in user. authenticate required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Auth
+//	@Security		BearerAuth
+//	@Success		200	{object}	helpers.VerificationEmailSent
+//	@Failure		409	{object}	helpers.EmailAlreadyVerified
+//	@Failure		429	{object}	helpers.VerificationCooldown
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@Router			/auth/resend-verification [post]
+func (u *UserHandlerImpl) ResendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	if err := u.UserService.ResendVerificationEmail(userID); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrEmailAlreadyVerified):
+			helpers.WriteJson(w, http.StatusConflict, helpers.M{"error": err.Error()})
+		case errors.Is(err, domain.ErrVerificationCooldown):
+			helpers.WriteJson(w, http.StatusTooManyRequests, helpers.M{"error": err.Error()})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"response": "verification email sent"})
+}
+
 // LoginHandler godoc
 //
 //	@Summary		Login
-//	@Description	Login with register credential
+//	@Description	Login with register credential. returns a short-lived access token and a refresh token to get new ones with
+//	@Description	when two-factor authentication is enabled it returns mfa_required and an mfa_token instead, which is exchanged with a code at /auth/login/mfa
+//	@Description	every failed login of an email doubles the wait before its next one, too many lock the account out for a while and too many from one address block the address. throttled logins get a Retry-After header
 //	@Accept			json
 //	@Produce		json
 //	@Tags			Auth
 //	@Param			loginRequest	body		entities.UserAuthRequest	true	"make sure send a valid email and password must be grater than 8 character"
 //	@Success		200				{object}	helpers.LoginOk
 //	@Failure		400				{object}	helpers.BadRequest
-//	@Failure		404				{object}	helpers.UserNotFound
+//	@Failure		401				{object}	helpers.InvalidCredentials
+//	@Failure		429				{object}	helpers.LoginThrottled
 //	@Failure		500				{object}	helpers.InternalServerError
 //	@Router			/auth/login [post]
 func (u *UserHandlerImpl) LoginHandler(w http.ResponseWriter, r *http.Request) {
@@ -74,27 +150,124 @@ func (u *UserHandlerImpl) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
 		return
 	}
-	user, err := u.UserService.GetUserByEmail(reqBody.Email)
-	if errors.Is(err, sql.ErrNoRows) {
-		helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "user not found"})
+	user, err := u.UserService.Authenticate(reqBody.Email, reqBody.Password, helpers.ClientIP(r))
+	if err != nil {
+		var throttled *domain.LoginThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			helpers.WriteJson(w, http.StatusTooManyRequests, helpers.M{"error": err.Error()})
+		case errors.Is(err, domain.ErrInvalidCredentials):
+			helpers.WriteJson(w, http.StatusUnauthorized, helpers.M{"error": err.Error()})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
 		return
 	}
-	if err := u.UserService.VerifyPassword(user.Password, reqBody.Password); err != nil {
-		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": "wrong password"})
+	if user.IsTOTPEnabled() {
+		challenge, err := u.UserService.CreateMFAChallenge(user)
+		if err != nil {
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+			return
+		}
+		helpers.WriteJson(w, http.StatusOK, challenge)
 		return
 	}
-	accessToken, err := u.UserService.CreateAccessToken(user.ID, user.Email)
+	tokens, err := u.UserService.CreateTokenPair(user, r.UserAgent(), helpers.ClientIP(r))
 	if err != nil {
 		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
 		return
 	}
-	helpers.WriteJson(w, http.StatusOK, helpers.M{"access_token": accessToken})
+	helpers.WriteJson(w, http.StatusOK, tokens)
+}
+
+// RefreshHandler godoc
+//
+//	@Summary		Refresh tokens
+//	@Description	Exchange a refresh token for a new access token and refresh token. every refresh token can only be used once, reusing one logs out every session that descends from the same login
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Auth
+//	@Param			refreshRequest	body		entities.RefreshTokenRequest	true	"refresh token from login or the last refresh"
+//	@Success		200				{object}	helpers.LoginOk
+//	@Failure		400				{object}	helpers.BadRequest
+//	@Failure		401				{object}	helpers.RefreshTokenInvalid
+//	@Failure		500				{object}	helpers.InternalServerError
+//	@Router			/auth/refresh [post]
+func (u *UserHandlerImpl) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	reqBody := new(entities.RefreshTokenRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	tokens, err := u.UserService.RefreshTokenPair(reqBody.RefreshToken, helpers.ClientIP(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrRefreshTokenInvalid), errors.Is(err, domain.ErrRefreshTokenReused):
+			helpers.WriteJson(w, http.StatusUnauthorized, helpers.M{"error": err.Error()})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, tokens)
+}
+
+// ForgotPasswordHandler godoc
+//
+//	@Summary		Forgot password
+//	@Description	Email a password reset link to the address if an account with it exists. the response is the same either way, so it does not tell which accounts exist
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Auth
+//	@Param			forgotPasswordRequest	body		entities.ForgotPasswordRequest	true	"email of the account"
+//	@Success		200						{object}	helpers.PasswordResetRequested
+//	@Failure		400						{object}	helpers.BadRequest
+//	@Router			/auth/forgot-password [post]
+func (u *UserHandlerImpl) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	reqBody := new(entities.ForgotPasswordRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	u.UserService.RequestPasswordReset(reqBody.Email)
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"response": "if an account with this email exists, a password reset link has been sent to it"})
+}
+
+// ResetPasswordHandler godoc
+//
+//	@Summary		Reset password
+//	@Description	Set a new password with the token of a reset link. every session of the account is logged out
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Auth
+//	@Param			resetPasswordRequest	body		entities.ResetPasswordRequest	true	"password must be grater than 8 character"
+//	@Success		200						{object}	helpers.PasswordReset
+//	@Failure		400						{object}	helpers.PasswordResetTokenInvalid
+//	@Failure		500						{object}	helpers.InternalServerError
+//	@Router			/auth/reset-password [post]
+func (u *UserHandlerImpl) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	reqBody := new(entities.ResetPasswordRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	if err := u.UserService.ResetPassword(reqBody.Token, reqBody.Password); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrPasswordResetTokenInvalid):
+			helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"response": "password reset"})
 }
 
 // LogoutHandler godoc
 //
 //	@Summary		Logout
-//	@Description	Logout and expire jwt token. authenticate required!
+//	@Description	Logout and revoke the session of the jwt token, together with its refresh tokens. authenticate required!
 //	@Accept			json
 //	@Produce		json
 //	@Tags			Auth
@@ -103,11 +276,267 @@ func (u *UserHandlerImpl) LoginHandler(w http.ResponseWriter, r *http.Request) {
 //	@Failure		500	{object}	helpers.InternalServerError
 //	@Router			/auth/logout [post]
 func (u *UserHandlerImpl) LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	tokenString := r.Header.Get("Authorization")
-	exp := r.Context().Value("exp").(float64)
-	if err := u.UserService.BlockJwtToken(tokenString, exp); err != nil {
+	if err := u.UserService.Logout(helpers.GetSessionJTI(r)); err != nil {
 		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
 		return
 	}
 	helpers.WriteJson(w, http.StatusOK, helpers.M{"response": "logged out"})
 }
+
+// LogoutAllHandler godoc
+//
+//	@Summary		Logout everywhere
+//	@Description	Logout every session of the user at once, expiring all of their jwt and refresh tokens. authenticate required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Auth
+//	@Security		BearerAuth
+//	@Success		200	{object}	helpers.LogoutOk
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@Router			/auth/logout-all [post]
+func (u *UserHandlerImpl) LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	if err := u.UserService.LogoutEverywhere(userID); err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"response": "logged out"})
+}
+
+// ChangePasswordHandler godoc
+//
+//	@Summary		Change password
+//	@Description	Change the password of the logged-in user. every other session is logged out and a new token pair is returned for this one. authenticate required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Auth
+//	@Security		BearerAuth
+//	@Param			changePasswordRequest	body		entities.ChangePasswordRequest	true	"new password must be grater than 8 character"
+//	@Success		200						{object}	helpers.LoginOk
+//	@Failure		400						{object}	helpers.PasswordIncorrect
+//	@Failure		500						{object}	helpers.InternalServerError
+//	@Router			/me/password [put]
+func (u *UserHandlerImpl) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	reqBody := new(entities.ChangePasswordRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	tokens, err := u.UserService.ChangePassword(userID, reqBody.OldPassword, reqBody.NewPassword, r.UserAgent(), helpers.ClientIP(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrPasswordIncorrect):
+			helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, tokens)
+}
+
+// GetMySessionsHandler godoc
+//
+//	@Summary		Get my sessions
+//	@Description	Get the sessions the logged-in user is signed in with, most recently seen first. the session of the request is marked as current. authenticate required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Auth
+//	@Security		BearerAuth
+//	@Success		200	{object}	helpers.AllSessions
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@Router			/me/sessions [get]
+func (u *UserHandlerImpl) GetMySessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	sessions, err := u.UserService.GetUserSessions(userID, helpers.GetSessionJTI(r))
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"sessions": sessions})
+}
+
+// RevokeSessionHandler godoc
+//
+//	@Summary		Revoke a session
+//	@Description	Log out one of the sessions of the logged-in user by ID, like a lost device. authenticate required!
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Auth
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Session ID"
+//	@Success		204	{object}	nil
+//	@Failure		404	{object}	helpers.SessionNotFound
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@Router			/me/sessions/{id} [delete]
+func (u *UserHandlerImpl) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	sessionID := chi.URLParam(r, "id")
+	if err := u.UserService.RevokeSession(userID, sessionID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "session not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusNoContent, nil)
+}
+
+// SetUserRoleHandler godoc
+//
+//	@Summary		Change the role of a user
+//	@Description	Make a user an admin, a moderator or a regular user. the user is logged out everywhere to pick up the new role. only admins can do it.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Admin
+//	@Security		BearerAuth
+//	@Param			id			path		int							true	"User ID"
+//	@Param			roleBody	body		entities.UserRoleRequest	true	"role must be user, moderator or admin. authenticate required!"
+//	@Success		200			{object}	helpers.UserRoleUpdated
+//	@Failure		400			{object}	helpers.BadRequest
+//	@Failure		403			{object}	helpers.Forbidden
+//	@Failure		404			{object}	helpers.UserNotFound
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/admin/users/{id}/role [put]
+func (u *UserHandlerImpl) SetUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+	reqBody := new(entities.UserRoleRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	user, err := u.UserService.SetUserRole(userID, reqBody.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "user not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, helpers.M{"id": user.ID, "email": user.Email, "role": user.Role})
+}
+
+// GetLoginLockoutHandler godoc
+//
+//	@Summary		Get the login lockout of a user
+//	@Description	Get the failed login attempts of a user and whether the account is locked out. only admins can do it.
+//	@Produce		json
+//	@Tags			Admin
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	helpers.LoginLockout
+//	@Failure		403	{object}	helpers.Forbidden
+//	@Failure		404	{object}	helpers.UserNotFound
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@Router			/admin/users/{id}/lockout [get]
+func (u *UserHandlerImpl) GetLoginLockoutHandler(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+	lockout, err := u.UserService.GetLoginLockout(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "user not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, lockout)
+}
+
+// ClearLoginLockoutHandler godoc
+//
+//	@Summary		Unlock a user
+//	@Description	Lift the login lockout of a user and forget its failed login attempts. only admins can do it.
+//	@Produce		json
+//	@Tags			Admin
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"User ID"
+//	@Success		204	{object}	nil
+//	@Failure		403	{object}	helpers.Forbidden
+//	@Failure		404	{object}	helpers.UserNotFound
+//	@Failure		500	{object}	helpers.InternalServerError
+//	@Router			/admin/users/{id}/lockout [delete]
+func (u *UserHandlerImpl) ClearLoginLockoutHandler(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+	if err := u.UserService.ClearLoginLockout(userID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "user not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusNoContent, nil)
+}
+
+// GetUserProfileHandler godoc
+//
+//	@Summary		Get the profile of a user
+//	@Description	Get the public profile of a user by username. karma is the sum of the votes other users cast on the published posts of the user.
+//	@Produce		json
+//	@Tags			Users
+//	@Param			username	path		string	true	"Username"
+//	@Success		200			{object}	helpers.UserProfile
+//	@Failure		404			{object}	helpers.UserNotFound
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/users/{username} [get]
+func (u *UserHandlerImpl) GetUserProfileHandler(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	profile, err := u.UserService.GetUserProfile(username)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			helpers.WriteJson(w, http.StatusNotFound, helpers.M{"error": "user not found"})
+		default:
+			helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		}
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, profile)
+}
+
+// UpdateProfileHandler godoc
+//
+//	@Summary		Update my profile
+//	@Description	Change the username, display name and bio of the current user. every field is replaced, send the current values to keep them.
+//	@Accept			json
+//	@Produce		json
+//	@Tags			Users
+//	@Security		BearerAuth
+//	@Param			profileBody	body		entities.UserProfileUpdateRequest	true	"username must be alphanumeric. authenticate required!"
+//	@Success		200			{object}	helpers.UserProfile
+//	@Failure		400			{object}	helpers.BadRequest
+//	@Failure		409			{object}	helpers.UsernameTaken
+//	@Failure		500			{object}	helpers.InternalServerError
+//	@Router			/me/profile [put]
+func (u *UserHandlerImpl) UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.GetUserID(r)
+	reqBody := new(entities.UserProfileUpdateRequest)
+	if err := helpers.ReadJson(r, reqBody); err != nil {
+		helpers.WriteJson(w, http.StatusBadRequest, helpers.M{"error": err.Error()})
+		return
+	}
+	// keeping the own username, or changing only its case, is not a conflict
+	owner, err := u.UserService.GetUserByUsername(reqBody.Username)
+	switch {
+	case err == nil && owner.ID != userID:
+		helpers.WriteJson(w, http.StatusConflict, helpers.M{"error": "username is already taken"})
+		return
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	profile, err := u.UserService.UpdateUserProfile(userID, reqBody)
+	if err != nil {
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	helpers.WriteJson(w, http.StatusOK, profile)
+}
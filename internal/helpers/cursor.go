@@ -0,0 +1,83 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// the postgres types a cursor value can be cast back to.
+const (
+	CursorTimestamp = "timestamptz"
+	CursorInteger   = "bigint"
+	CursorFloat     = "double precision"
+)
+
+// cursorTimeLayouts are the text forms postgres gives a timestamptz in, with
+// and without a minutes part in the offset.
+var cursorTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999-07",
+	"2006-01-02 15:04:05.999999-07:00",
+}
+
+// Cursor points at the row a keyset page starts after. it is handed to
+// clients as an opaque base64 string.
+type Cursor struct {
+	Sort     string `json:"s"`
+	Value    string `json:"v"`
+	ID       string `json:"id"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+func EncodeCursor(c Cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor decodes a cursor handed out for sort and checks that its id and
+// value are valid literals of the types the query casts them to.
+func DecodeCursor(s, sort, valueType string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := strconv.ParseInt(c.ID, 10, 32); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if !validCursorValue(c.Value, valueType) {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+func validCursorValue(value, valueType string) bool {
+	switch valueType {
+	case CursorTimestamp:
+		for _, layout := range cursorTimeLayouts {
+			if _, err := time.Parse(layout, value); err == nil {
+				return true
+			}
+		}
+		return false
+	case CursorInteger:
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case CursorFloat:
+		// go also takes hex floats and underscores, postgres does not.
+		if strings.ContainsAny(value, "xX_") {
+			return false
+		}
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		cursor    Cursor
+		valueType string
+	}{
+		{
+			name:      "timestamp",
+			cursor:    Cursor{Sort: "-created_at", Value: "2023-10-05 14:30:45.123456+00", ID: "42"},
+			valueType: CursorTimestamp,
+		},
+		{
+			name:      "timestamp with a minutes offset",
+			cursor:    Cursor{Sort: "created_at", Value: "2023-10-05 20:00:45+05:30", ID: "42"},
+			valueType: CursorTimestamp,
+		},
+		{
+			name:      "float",
+			cursor:    Cursor{Sort: "-hot", Value: "4.123456789012345", ID: "3"},
+			valueType: CursorFloat,
+		},
+		{
+			name:      "float exponent",
+			cursor:    Cursor{Sort: "best", Value: "1e-05", ID: "3"},
+			valueType: CursorFloat,
+		},
+		{
+			name:      "integer",
+			cursor:    Cursor{Sort: "vote_count", Value: "-12", ID: "7"},
+			valueType: CursorInteger,
+		},
+		{
+			name:      "backward",
+			cursor:    Cursor{Sort: "-vote_count", Value: "30", ID: "7", Backward: true},
+			valueType: CursorInteger,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(EncodeCursor(tt.cursor), tt.cursor.Sort, tt.valueType)
+			if err != nil {
+				t.Fatalf("DecodeCursor: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.cursor) {
+				t.Errorf("DecodeCursor = %+v, want %+v", *got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	encodeJSON := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name      string
+		cursor    string
+		sort      string
+		valueType string
+	}{
+		{
+			name:      "malformed base64",
+			cursor:    "not base64!",
+			sort:      "vote_count",
+			valueType: CursorInteger,
+		},
+		{
+			name:      "padded base64",
+			cursor:    base64.URLEncoding.EncodeToString([]byte(`{"s":"vote_count","v":"1","id":"1"}`)),
+			sort:      "vote_count",
+			valueType: CursorInteger,
+		},
+		{
+			name:      "not json",
+			cursor:    encodeJSON("vote_count:1:1"),
+			sort:      "vote_count",
+			valueType: CursorInteger,
+		},
+		{
+			name:      "other sort",
+			cursor:    EncodeCursor(Cursor{Sort: "created_at", Value: "1", ID: "1"}),
+			sort:      "vote_count",
+			valueType: CursorInteger,
+		},
+		{
+			name:      "other direction",
+			cursor:    EncodeCursor(Cursor{Sort: "-vote_count", Value: "1", ID: "1"}),
+			sort:      "vote_count",
+			valueType: CursorInteger,
+		},
+		{
+			name:      "missing id",
+			cursor:    EncodeCursor(Cursor{Sort: "vote_count", Value: "1"}),
+			sort:      "vote_count",
+			valueType: CursorInteger,
+		},
+		{
+			name:      "id is not an integer",
+			cursor:    EncodeCursor(Cursor{Sort: "vote_count", Value: "1", ID: "1 OR 1=1"}),
+			sort:      "vote_count",
+			valueType: CursorInteger,
+		},
+		{
+			name:      "id out of range",
+			cursor:    EncodeCursor(Cursor{Sort: "vote_count", Value: "1", ID: "9999999999"}),
+			sort:      "vote_count",
+			valueType: CursorInteger,
+		},
+		{
+			name:      "integer value is not an integer",
+			cursor:    EncodeCursor(Cursor{Sort: "vote_count", Value: "1.5", ID: "1"}),
+			sort:      "vote_count",
+			valueType: CursorInteger,
+		},
+		{
+			name:      "timestamp value is not a timestamp",
+			cursor:    EncodeCursor(Cursor{Sort: "created_at", Value: "yesterday", ID: "1"}),
+			sort:      "created_at",
+			valueType: CursorTimestamp,
+		},
+		{
+			name:      "timestamp value in another format",
+			cursor:    EncodeCursor(Cursor{Sort: "created_at", Value: "2023-10-05T14:30:45Z", ID: "1"}),
+			sort:      "created_at",
+			valueType: CursorTimestamp,
+		},
+		{
+			name:      "float value is not a number",
+			cursor:    EncodeCursor(Cursor{Sort: "hot", Value: "abc", ID: "1"}),
+			sort:      "hot",
+			valueType: CursorFloat,
+		},
+		{
+			name:      "hex float",
+			cursor:    EncodeCursor(Cursor{Sort: "hot", Value: "0x1p-2", ID: "1"}),
+			sort:      "hot",
+			valueType: CursorFloat,
+		},
+		{
+			name:      "unknown value type",
+			cursor:    EncodeCursor(Cursor{Sort: "title", Value: "hello", ID: "1"}),
+			sort:      "title",
+			valueType: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := DecodeCursor(tt.cursor, tt.sort, tt.valueType)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor = %+v, %v, want %v", cursor, err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestReadQsCursor(t *testing.T) {
+	valueTypes := map[string]string{"vote_count": CursorInteger}
+	valid := EncodeCursor(Cursor{Sort: "-vote_count", Value: "3", ID: "9"})
+	tests := []struct {
+		name      string
+		query     map[string][]string
+		wantUse   bool
+		wantFirst bool
+		wantError bool
+	}{
+		{name: "absent", query: map[string][]string{}},
+		{name: "first page", query: map[string][]string{"cursor": {""}}, wantUse: true, wantFirst: true},
+		{name: "next page", query: map[string][]string{"cursor": {valid}}, wantUse: true},
+		{name: "invalid", query: map[string][]string{"cursor": {"garbage"}}, wantUse: true, wantFirst: true, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator()
+			cursor, use := v.ReadQsCursor(tt.query, "cursor", "-vote_count", valueTypes)
+			if use != tt.wantUse {
+				t.Errorf("use = %v, want %v", use, tt.wantUse)
+			}
+			if (cursor == nil) != (tt.wantFirst || !tt.wantUse) {
+				t.Errorf("cursor = %+v", cursor)
+			}
+			if v.IsValid() == tt.wantError {
+				t.Errorf("errors = %v, want error %v", v.Errors, tt.wantError)
+			}
+		})
+	}
+}
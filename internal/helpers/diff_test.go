@@ -0,0 +1,108 @@
+package helpers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+)
+
+func equal(text string) model.DiffLine  { return model.DiffLine{Op: DiffEqual, Text: text} }
+func insert(text string) model.DiffLine { return model.DiffLine{Op: DiffInsert, Text: text} }
+func remove(text string) model.DiffLine { return model.DiffLine{Op: DiffDelete, Text: text} }
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want []model.DiffLine
+	}{
+		{
+			name: "identical",
+			old:  "a\nb\nc",
+			new:  "a\nb\nc",
+			want: []model.DiffLine{equal("a"), equal("b"), equal("c")},
+		},
+		{
+			name: "both empty",
+			want: []model.DiffLine{},
+		},
+		{
+			name: "insert only",
+			old:  "a\nc",
+			new:  "a\nb\nc\nd",
+			want: []model.DiffLine{equal("a"), insert("b"), equal("c"), insert("d")},
+		},
+		{
+			name: "insert into empty",
+			new:  "a\nb",
+			want: []model.DiffLine{insert("a"), insert("b")},
+		},
+		{
+			name: "delete only",
+			old:  "a\nb\nc\nd",
+			new:  "b\nd",
+			want: []model.DiffLine{remove("a"), equal("b"), remove("c"), equal("d")},
+		},
+		{
+			name: "delete everything",
+			old:  "a\nb",
+			want: []model.DiffLine{remove("a"), remove("b")},
+		},
+		{
+			name: "interleaved",
+			old:  "a\nb\nc\nd",
+			new:  "a\nx\nc\ny",
+			want: []model.DiffLine{equal("a"), remove("b"), insert("x"), equal("c"), remove("d"), insert("y")},
+		},
+		{
+			name: "windows line endings",
+			old:  "a\r\nb",
+			new:  "a\nc",
+			want: []model.DiffLine{equal("a"), remove("b"), insert("c")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := LineDiff(tt.old, tt.new)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LineDiff(%q, %q) = %v, want %v", tt.old, tt.new, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLineDiffReproducesBothSides checks that the equal and deleted lines of a
+// diff give back the old text and the equal and inserted lines the new one.
+func TestLineDiffReproducesBothSides(t *testing.T) {
+	tests := []struct {
+		old string
+		new string
+	}{
+		{old: "the quick\nbrown fox\njumps over\nthe lazy dog", new: "the quick\nred fox\njumps over\nthe lazy dog\nand runs"},
+		{old: "x\ny\nz\nx\ny\nz", new: "y\nx\nz\nz\ny\nx"},
+		{old: "one\ntwo\nthree", new: "three\ntwo\none"},
+	}
+	for _, tt := range tests {
+		var old, new []string
+		for _, line := range LineDiff(tt.old, tt.new) {
+			switch line.Op {
+			case DiffEqual:
+				old = append(old, line.Text)
+				new = append(new, line.Text)
+			case DiffDelete:
+				old = append(old, line.Text)
+			case DiffInsert:
+				new = append(new, line.Text)
+			}
+		}
+		if got := strings.Join(old, "\n"); got != tt.old {
+			t.Errorf("old side = %q, want %q", got, tt.old)
+		}
+		if got := strings.Join(new, "\n"); got != tt.new {
+			t.Errorf("new side = %q, want %q", got, tt.new)
+		}
+	}
+}
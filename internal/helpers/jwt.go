@@ -5,13 +5,10 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func IsTokenValid(reqToken string, secretKey string) (*jwt.Token, error) {
-	token, err := jwt.Parse(reqToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrSignatureInvalid
-		}
-		return []byte(secretKey), nil
-	})
+// IsTokenValid parses a token and checks its signature with the key keyFunc
+// picks for it, which also decides what algorithms are accepted.
+func IsTokenValid(reqToken string, keyFunc jwt.Keyfunc) (*jwt.Token, error) {
+	token, err := jwt.Parse(reqToken, keyFunc)
 	if err != nil {
 		return nil, err
 	}
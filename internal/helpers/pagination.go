@@ -10,6 +10,8 @@ type PaginateFilter struct {
 	PageSize     int
 	Sort         string
 	SortSafeList []string
+	UseCursor    bool
+	Cursor       *Cursor
 }
 
 func (p *PaginateFilter) Validate(v *Validator) {
@@ -18,6 +20,7 @@ func (p *PaginateFilter) Validate(v *Validator) {
 	v.Check(p.PageSize > 0, "page_size", "must be greater than zero")
 	v.Check(p.PageSize <= 100, "page_size", "must be a maximum of 100")
 	v.Check(v.In(p.Sort, p.SortSafeList...), "sort", "invalid sort value")
+	v.Check(p.Cursor == nil || p.Cursor.Sort == p.Sort, "cursor", "does not match sort value")
 }
 
 func (p *PaginateFilter) Limit() int {
@@ -45,11 +48,13 @@ func (p *PaginateFilter) SortDirection() string {
 }
 
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty" example:"3"`
-	PageSize     int `json:"page_size,omitempty" example:"2"`
-	FirstPage    int `json:"first_page,omitempty" example:"1"`
-	LastPage     int `json:"last_page,omitempty" example:"12"`
-	TotalRecords int `json:"total_records,omitempty" example:"1200"`
+	CurrentPage  int    `json:"current_page,omitempty" example:"3"`
+	PageSize     int    `json:"page_size,omitempty" example:"2"`
+	FirstPage    int    `json:"first_page,omitempty" example:"1"`
+	LastPage     int    `json:"last_page,omitempty" example:"12"`
+	TotalRecords int    `json:"total_records,omitempty" example:"1200"`
+	NextCursor   string `json:"next_cursor,omitempty" example:"eyJzIjoiLXZvdGVfY291bnQiLCJ2IjoiMTIiLCJpZCI6IjQyIn0"`
+	PrevCursor   string `json:"prev_cursor,omitempty" example:"eyJzIjoiLXZvdGVfY291bnQiLCJ2IjoiMzAiLCJpZCI6IjciLCJiIjp0cnVlfQ"`
 }
 
 func CalculateMetadata(totalRecords, page, pageSize int) Metadata {
@@ -64,3 +69,19 @@ func CalculateMetadata(totalRecords, page, pageSize int) Metadata {
 		TotalRecords: totalRecords,
 	}
 }
+
+// CalculateCursorMetadata builds the metadata of a keyset page. first and last
+// are the cursors of the first and last row of the page, hasMore reports
+// whether a row exists beyond the page in the direction it was read.
+func CalculateCursorMetadata(filter *PaginateFilter, first, last Cursor, hasMore bool) Metadata {
+	metadata := Metadata{PageSize: filter.PageSize}
+	backward := filter.Cursor != nil && filter.Cursor.Backward
+	if hasMore || backward {
+		metadata.NextCursor = EncodeCursor(last)
+	}
+	if filter.Cursor != nil && (hasMore || !backward) {
+		first.Backward = true
+		metadata.PrevCursor = EncodeCursor(first)
+	}
+	return metadata
+}
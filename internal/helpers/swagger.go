@@ -14,6 +14,18 @@ type UserExists struct {
 	Error string `json:"error" example:"user already exists"`
 }
 
+type UsernameTaken struct {
+	Error string `json:"error" example:"username is already taken"`
+}
+
+type UserProfile model.UserProfile
+
+type UserExport model.UserExport
+
+type PasswordInvalid struct {
+	Error string `json:"error" example:"password is incorrect"`
+}
+
 type InternalServerError struct {
 	Error string `json:"error" example:"Internal Server Error"`
 }
@@ -22,8 +34,115 @@ type UserNotFound struct {
 	Error string `json:"error" example:"user not found"`
 }
 
+type UserRoleUpdated struct {
+	ID    string `json:"id" example:"123"`
+	Email string `json:"email" example:"james@gmail.com"`
+	Role  string `json:"role" example:"moderator"`
+}
+
+type APIKey model.APIKey
+
+type CreatedAPIKey model.CreatedAPIKey
+
+type AllAPIKeys struct {
+	APIKeys []model.APIKey `json:"api_keys"`
+}
+
+type APIKeyNotFound struct {
+	Error string `json:"error" example:"api key not found"`
+}
+
 type LoginOk struct {
-	AccessToken string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	AccessToken  string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string `json:"refresh_token" example:"Zm9vYmFyYmF6cXV4cXV1eGNvcmdlZ3JhdWx0Z2FycGx5"`
+}
+
+type RefreshTokenInvalid struct {
+	Error string `json:"error" example:"refresh token is invalid or expired"`
+}
+
+type EmailVerified struct {
+	Response string `json:"response" example:"email verified"`
+}
+
+type VerificationEmailSent struct {
+	Response string `json:"response" example:"verification email sent"`
+}
+
+type VerificationTokenInvalid struct {
+	Error string `json:"error" example:"verification token is invalid or expired"`
+}
+
+type EmailAlreadyVerified struct {
+	Error string `json:"error" example:"email is already verified"`
+}
+
+type EmailNotVerified struct {
+	Error string `json:"error" example:"email address is not verified"`
+}
+
+type VerificationCooldown struct {
+	Error string `json:"error" example:"a verification email was sent recently, try again later"`
+}
+
+type InvalidCredentials struct {
+	Error string `json:"error" example:"invalid credentials"`
+}
+
+type LoginThrottled struct {
+	Error string `json:"error" example:"too many failed login attempts, try again later"`
+}
+
+type LoginLockout model.LoginLockout
+
+type PasswordResetRequested struct {
+	Response string `json:"response" example:"if an account with this email exists, a password reset link has been sent to it"`
+}
+
+type PasswordReset struct {
+	Response string `json:"response" example:"password reset"`
+}
+
+type PasswordResetTokenInvalid struct {
+	Error string `json:"error" example:"password reset token is invalid or expired"`
+}
+
+type PasswordIncorrect struct {
+	Error string `json:"error" example:"old password is incorrect"`
+}
+
+type TOTPEnrollment model.TOTPEnrollment
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"k7qd-3mzp,x2fa-9ven"`
+}
+
+type TOTPCodeInvalid struct {
+	Error string `json:"error" example:"two-factor code is invalid"`
+}
+
+type TOTPAlreadyEnabled struct {
+	Error string `json:"error" example:"two-factor authentication is already enabled"`
+}
+
+type TOTPNotEnabled struct {
+	Error string `json:"error" example:"two-factor authentication is not enabled"`
+}
+
+type MFATokenInvalid struct {
+	Error string `json:"error" example:"mfa token is invalid or expired"`
+}
+
+type MFAAttemptsExceeded struct {
+	Error string `json:"error" example:"too many invalid two-factor codes, try again later"`
+}
+
+type AllSessions struct {
+	Sessions []model.Session `json:"sessions"`
+}
+
+type SessionNotFound struct {
+	Error string `json:"error" example:"session not found"`
 }
 
 type LogoutOk struct {
@@ -31,9 +150,13 @@ type LogoutOk struct {
 }
 
 type PostQueryParams struct {
-	Page     *int    `json:"page"        example:"1" default:"1"`
-	PageSize *int    `json:"page_size"   example:"3" default:"5"`
-	Sort     *string `json:"sort"        example:"created_at -created_at vote_count -vote_count" default:"-vote_count"`
+	Page        *int      `json:"page"        example:"1" default:"1"`
+	PageSize    *int      `json:"page_size"   example:"3" default:"5"`
+	Sort        *string   `json:"sort"        example:"created_at -created_at vote_count -vote_count -hot -best -rising -controversial" default:"-vote_count"`
+	CommunityID *int      `json:"community_id" example:"1"`
+	Tag         *[]string `json:"tag"          example:"golang"`
+	TagMode     *string   `json:"tag_mode"     example:"any all" default:"any"`
+	Cursor      *string   `json:"cursor"       example:"eyJzIjoiLXZvdGVfY291bnQiLCJ2IjoiMTIiLCJpZCI6IjQyIn0"`
 }
 
 type AllPosts struct {
@@ -41,12 +164,64 @@ type AllPosts struct {
 	Metadata Metadata     `json:"metadata"`
 }
 
+type PostSearchQueryParams struct {
+	Query       *string   `json:"q"            example:"golang generics"`
+	Page        *int      `json:"page"         example:"1" default:"1"`
+	PageSize    *int      `json:"page_size"    example:"3" default:"5"`
+	Sort        *string   `json:"sort"         example:"rank -rank created_at -created_at vote_count -vote_count" default:"-rank"`
+	CommunityID *int      `json:"community_id" example:"1"`
+	Tag         *[]string `json:"tag"          example:"golang"`
+	TagMode     *string   `json:"tag_mode"     example:"any all" default:"any"`
+}
+
+type PostSearchResults struct {
+	Posts    []model.PostSearchResult `json:"posts"`
+	Metadata Metadata                 `json:"metadata"`
+}
+
 type PostNotFound struct {
 	Error string `json:"error" example:"post not found"`
 }
 
 type Post model.Post
 
+type DraftQueryParams struct {
+	Page     *int    `json:"page"      example:"1" default:"1"`
+	PageSize *int    `json:"page_size" example:"3" default:"10"`
+	Sort     *string `json:"sort"      example:"created_at -created_at publish_at -publish_at" default:"-created_at"`
+}
+
+type UserPostQueryParams struct {
+	Page     *int    `json:"page"      example:"1" default:"1"`
+	PageSize *int    `json:"page_size" example:"3" default:"10"`
+	Sort     *string `json:"sort"      example:"created_at -created_at vote_count -vote_count -hot -best -rising -controversial" default:"-created_at"`
+	Cursor   *string `json:"cursor"    example:"eyJzIjoiLWNyZWF0ZWRfYXQiLCJ2IjoiMjAyMy0xMC0wNVQxNDozMDo0NVoiLCJpZCI6IjQyIn0"`
+}
+
+type SavedPostQueryParams struct {
+	Page     *int    `json:"page"      example:"1" default:"1"`
+	PageSize *int    `json:"page_size" example:"3" default:"10"`
+	Sort     *string `json:"sort"      example:"saved_at -saved_at created_at -created_at" default:"-saved_at"`
+}
+
+type PostSaved struct {
+	Response string `json:"response" example:"post saved"`
+}
+
+type PostAlreadyPublished struct {
+	Error string `json:"error" example:"post is already published"`
+}
+
+type AllPostRevisions struct {
+	Revisions []model.PostRevision `json:"revisions"`
+}
+
+type PostRevisionDiff model.PostRevisionDiff
+
+type RevisionNotFound struct {
+	Error string `json:"error" example:"revision not found"`
+}
+
 type Forbidden struct {
 	Error string `json:"error" example:"Forbidden"`
 }
@@ -54,3 +229,70 @@ type Forbidden struct {
 type VoteSuccessful struct {
 	Response string `json:"response" example:"successful"`
 }
+
+type CommentQueryParams struct {
+	Page     *int    `json:"page"        example:"1" default:"1"`
+	PageSize *int    `json:"page_size"   example:"3" default:"10"`
+	Sort     *string `json:"sort"        example:"created_at -created_at vote_count -vote_count" default:"-vote_count"`
+}
+
+type AllComments struct {
+	Comments []model.Comment `json:"comments"`
+	Metadata Metadata        `json:"metadata"`
+}
+
+type CommentNotFound struct {
+	Error string `json:"error" example:"comment not found"`
+}
+
+type Comment model.Comment
+
+type CommunityQueryParams struct {
+	Page     *int    `json:"page"        example:"1" default:"1"`
+	PageSize *int    `json:"page_size"   example:"3" default:"10"`
+	Sort     *string `json:"sort"        example:"name -name created_at -created_at" default:"name"`
+}
+
+type AllCommunities struct {
+	Communities []model.Community `json:"communities"`
+	Metadata    Metadata          `json:"metadata"`
+}
+
+type CommunityNotFound struct {
+	Error string `json:"error" example:"community not found"`
+}
+
+type CommunityExists struct {
+	Error string `json:"error" example:"community already exists"`
+}
+
+type Community model.Community
+
+type TagQueryParams struct {
+	Page     *int    `json:"page"      example:"1" default:"1"`
+	PageSize *int    `json:"page_size" example:"3" default:"20"`
+	Sort     *string `json:"sort"      example:"post_count -post_count name -name" default:"-post_count"`
+}
+
+type AllTags struct {
+	Tags     []model.Tag `json:"tags"`
+	Metadata Metadata    `json:"metadata"`
+}
+
+type Attachment model.Attachment
+
+type AttachmentNotFound struct {
+	Error string `json:"error" example:"attachment not found"`
+}
+
+type AttachmentTooLarge struct {
+	Error string `json:"error" example:"file is too large"`
+}
+
+type AttachmentTypeNotAllowed struct {
+	Error string `json:"error" example:"file type is not allowed"`
+}
+
+type TooManyAttachments struct {
+	Error string `json:"error" example:"post has reached the maximum number of attachments"`
+}
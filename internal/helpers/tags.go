@@ -0,0 +1,22 @@
+package helpers
+
+import "strings"
+
+// NormalizeTags lowercases and trims tags, dropping empty and duplicate ones.
+// a nil slice stays nil, so callers can tell "no tags sent" from "no tags".
+func NormalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
@@ -0,0 +1,101 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 seed of the RFC 6238 test vectors,
+// "12345678901234567890" in base32.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// RFC 6238 Appendix B lists 8 digit codes; the 6 digit codes used here are
+	// their last six digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, tt.unix/TOTPPeriod)
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+		step, ok := ValidateTOTP(rfc6238Secret, tt.want, time.Unix(tt.unix, 0))
+		if !ok || step != tt.unix/TOTPPeriod {
+			t.Errorf("ValidateTOTP(%d) = %d, %v, want %d, true", tt.unix, step, ok, tt.unix/TOTPPeriod)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / TOTPPeriod
+	tests := []struct {
+		name   string
+		offset int64
+		valid  bool
+	}{
+		{name: "current step", offset: 0, valid: true},
+		{name: "previous step", offset: -1, valid: true},
+		{name: "next step", offset: 1, valid: true},
+		{name: "two steps behind", offset: -2},
+		{name: "two steps ahead", offset: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := TOTPCode(rfc6238Secret, current+tt.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			step, ok := ValidateTOTP(rfc6238Secret, code, now)
+			if ok != tt.valid {
+				t.Fatalf("ValidateTOTP ok = %v, want %v", ok, tt.valid)
+			}
+			if ok && step != current+tt.offset {
+				t.Errorf("ValidateTOTP step = %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPRejects(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{name: "wrong code", secret: rfc6238Secret, code: "123456"},
+		{name: "too short", secret: rfc6238Secret, code: "05047"},
+		{name: "eight digits", secret: rfc6238Secret, code: "14050471"},
+		{name: "invalid secret", secret: "not base32!", code: "050471"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, now); ok {
+				t.Errorf("ValidateTOTP(%q, %q) = true, want false", tt.secret, tt.code)
+			}
+		})
+	}
+}
+
+func TestTOTPCodeLowercaseSecret(t *testing.T) {
+	got, err := TOTPCode("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "287082" {
+		t.Errorf("TOTPCode = %s, want 287082", got)
+	}
+}
@@ -1,9 +1,40 @@
 package helpers
 
 import (
+	"net"
 	"net/http"
 )
 
 func GetUserID(r *http.Request) string {
 	return r.Context().Value("user_id").(string)
 }
+
+// GetOptionalUserID returns the id of the authenticated user, or an empty
+// string for anonymous requests behind OptionalJwtAuth.
+func GetOptionalUserID(r *http.Request) string {
+	userID, _ := r.Context().Value("user_id").(string)
+	return userID
+}
+
+// GetUserRole returns the role of the authenticated user, or an empty string
+// for anonymous requests behind OptionalJwtAuth.
+func GetUserRole(r *http.Request) string {
+	role, _ := r.Context().Value("role").(string)
+	return role
+}
+
+// GetSessionJTI returns the jti of the session the request was authenticated with.
+func GetSessionJTI(r *http.Request) string {
+	jti, _ := r.Context().Value("jti").(string)
+	return jti
+}
+
+// ClientIP returns the address of the client that made the request. behind a
+// trusted proxy it is the one middleware.RealIP took from the forwarded headers.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
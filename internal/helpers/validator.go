@@ -3,6 +3,7 @@ package helpers
 import (
 	"net/url"
 	"strconv"
+	"strings"
 )
 
 type Map map[string]string
@@ -62,3 +63,22 @@ func (v *Validator) ReadQsInt(qs url.Values, key string, defaultValue int) int {
 	}
 	return i
 }
+
+// ReadQsCursor reports whether key is present in the query string and decodes
+// its value for sort, checking the value against the type valueTypes maps the
+// sort to. a present but empty key asks for the first keyset page.
+func (v *Validator) ReadQsCursor(qs url.Values, key, sort string, valueTypes map[string]string) (*Cursor, bool) {
+	if !qs.Has(key) {
+		return nil, false
+	}
+	k := qs.Get(key)
+	if k == "" {
+		return nil, true
+	}
+	cursor, err := DecodeCursor(k, sort, valueTypes[strings.TrimPrefix(sort, "-")])
+	if err != nil {
+		v.Add(key, "must be a valid cursor")
+		return nil, true
+	}
+	return cursor, true
+}
@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"github.com/arshamroshannejad/task-rootext/config"
+	"github.com/arshamroshannejad/task-rootext/internal/repository"
+	"github.com/arshamroshannejad/task-rootext/internal/service"
+	"github.com/arshamroshannejad/task-rootext/internal/storage"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Job is a task the server runs periodically in the background.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func() error
+	// Exclusive jobs run on a single replica per interval. the replica that
+	// takes the redis lock runs the job and the others skip that tick.
+	Exclusive bool
+}
+
+func Start(db *sql.DB, redisDB *redis.Client, fileStorage storage.Storage, zapLogger *zap.Logger, cfg *config.Config) {
+	postRepository := repository.NewPostRepository(db)
+	postService := service.NewPostService(postRepository, redisDB, zapLogger, cfg)
+	attachmentRepository := repository.NewAttachmentRepository(db)
+	attachmentService := service.NewAttachmentService(attachmentRepository, postService, fileStorage, zapLogger, cfg)
+	schedule(redisDB, zapLogger,
+		Job{
+			Name:     "refresh rising scores",
+			Interval: cfg.Ranking.RisingRefreshInterval,
+			Run:      postService.RefreshRisingScores,
+		},
+		Job{
+			Name:     "purge deleted posts",
+			Interval: cfg.Posts.PurgeInterval,
+			Run:      postService.PurgeDeletedPosts,
+		},
+		Job{
+			Name:      "publish scheduled posts",
+			Interval:  cfg.Posts.PublishInterval,
+			Run:       postService.PublishScheduledPosts,
+			Exclusive: true,
+		},
+		Job{
+			Name:      "cleanup detached attachments",
+			Interval:  cfg.Attachments.CleanupInterval,
+			Run:       attachmentService.CleanupDetachedAttachments,
+			Exclusive: true,
+		},
+	)
+}
+
+func schedule(redisDB *redis.Client, zapLogger *zap.Logger, jobs ...Job) {
+	for _, job := range jobs {
+		go func() {
+			ticker := time.NewTicker(job.Interval)
+			defer ticker.Stop()
+			for {
+				if !job.Exclusive || acquireLock(redisDB, zapLogger, job) {
+					if err := job.Run(); err != nil {
+						zapLogger.Error("Background job failed", zap.String("Job", job.Name), zap.Error(err))
+					}
+				}
+				<-ticker.C
+			}
+		}()
+		zapLogger.Info("Background job scheduled", zap.String("Job", job.Name), zap.Duration("Interval", job.Interval))
+	}
+}
+
+// acquireLock takes the lock of a job for one interval. the lock is left to
+// expire instead of being released, so replicas with shifted tickers can not
+// run the job twice within the same interval.
+func acquireLock(redisDB *redis.Client, zapLogger *zap.Logger, job Job) bool {
+	acquired, err := redisDB.SetNX(context.Background(), "job_lock:"+job.Name, time.Now().Unix(), job.Interval).Result()
+	if err != nil {
+		zapLogger.Error("Failed to acquire background job lock", zap.String("Job", job.Name), zap.Error(err))
+		return false
+	}
+	return acquired
+}
@@ -0,0 +1,177 @@
+// Package keyring holds the keys access tokens are signed with. tokens carry
+// the id of their key in the kid header, so several keys are trusted at once
+// and the signing key can be rotated without rejecting the tokens signed with
+// the previous one.
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/arshamroshannejad/task-rootext/config"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// minRSABits is the smallest RSA key that is accepted for signing tokens.
+const minRSABits = 2048
+
+// minSecretLength is the shortest app secret accepted for HS256 tokens.
+const minSecretLength = 32
+
+// Key is a key of the keyring. keys that were loaded from a public key file
+// have no private key and only verify tokens, like a retired signing key.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	private any
+	public  any
+}
+
+type Keyring struct {
+	active      *Key
+	keys        map[string]*Key
+	ids         []string
+	secret      []byte
+	acceptHS256 bool
+}
+
+// New loads every .pem file in jwt.KeysDir, the file name without extension
+// is the kid of the key. the active key signs new tokens, it is jwt.ActiveKeyID
+// or the last private key by file name, so dated file names rotate on their
+// own. without a keys directory it refuses to start, unless jwt.InsecureHS256
+// asks for HS256 tokens signed with the app secret for local development.
+func New(cfg *config.Config, zapLogger *zap.Logger) (*Keyring, error) {
+	k := &Keyring{
+		keys:        make(map[string]*Key),
+		secret:      []byte(cfg.App.Secret),
+		acceptHS256: cfg.JWT.AcceptHS256,
+	}
+	if cfg.JWT.KeysDir == "" && !cfg.JWT.InsecureHS256 {
+		return nil, errors.New("no jwt keys directory configured, set JWT_KEYS_DIR")
+	}
+	// the secret only comes from the environment, an unset one must not become a key
+	if (cfg.JWT.KeysDir == "" || k.acceptHS256) && len(k.secret) < minSecretLength {
+		return nil, fmt.Errorf("HS256 tokens need an APP_SECRET of at least %d characters", minSecretLength)
+	}
+	if cfg.JWT.KeysDir == "" {
+		zapLogger.Warn("No jwt keys directory configured, signing access tokens with HS256 and the app secret, do not use this in production")
+		k.acceptHS256 = true
+		return k, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(cfg.JWT.KeysDir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		key, err := loadKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("load jwt key %s: %w", path, err)
+		}
+		k.keys[key.ID] = key
+		k.ids = append(k.ids, key.ID)
+	}
+	activeID := cfg.JWT.ActiveKeyID
+	if activeID == "" {
+		for _, id := range k.ids {
+			if k.keys[id].private != nil {
+				activeID = id
+			}
+		}
+	}
+	active, ok := k.keys[activeID]
+	if !ok || active.private == nil {
+		return nil, fmt.Errorf("no private jwt key %q in %s", activeID, cfg.JWT.KeysDir)
+	}
+	k.active = active
+	zapLogger.Info("JWT keys loaded", zap.Strings("KeyIDs", k.ids), zap.String("ActiveKeyID", active.ID))
+	return k, nil
+}
+
+// Sign signs the claims with the active key and puts its id in the kid header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	if k.active == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.secret)
+	}
+	token := jwt.NewWithClaims(k.active.Method, claims)
+	token.Header["kid"] = k.active.ID
+	return token.SignedString(k.active.private)
+}
+
+// ParseFor parses a token that was signed with Sign for a purpose other than
+// access, like the second login step, into claims. such tokens carry their
+// purpose as audience, so a token is only accepted for the purpose it was
+// issued for, and they must expire.
+func (k *Keyring) ParseFor(purpose, tokenString string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(tokenString, claims, k.Keyfunc, jwt.WithAudience(purpose), jwt.WithExpirationRequired())
+	return err
+}
+
+// Keyfunc returns the key a token is verified with, for jwt.Parse. a token with
+// a kid needs a known key of the same algorithm. a token without one is an
+// HS256 token signed with the app secret, which is only accepted while
+// jwt.AcceptHS256 is on or no keys are configured.
+func (k *Keyring) Keyfunc(token *jwt.Token) (any, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		if token.Method == jwt.SigningMethodHS256 && k.acceptHS256 {
+			return k.secret, nil
+		}
+		return nil, jwt.ErrTokenUnverifiable
+	}
+	key, ok := k.keys[kid]
+	if !ok || token.Method.Alg() != key.Method.Alg() {
+		return nil, jwt.ErrTokenUnverifiable
+	}
+	return key.public, nil
+}
+
+func loadKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newKey(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), parsed)
+}
+
+func newKey(id string, parsed any) (*Key, error) {
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("rsa key is shorter than %d bits", minRSABits)
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, private: key, public: &key.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, public: key}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, private: key, public: key.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, public: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+	}
+}
@@ -0,0 +1,320 @@
+package keyring
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arshamroshannejad/task-rootext/config"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// keyFiles generates an RSA and an Ed25519 key into a temp dir, plus the
+// public half of a retired RSA key.
+type keyFiles struct {
+	dir     string
+	rsa     *rsa.PrivateKey
+	ed      ed25519.PrivateKey
+	retired *rsa.PrivateKey
+}
+
+func newKeyFiles(t *testing.T) *keyFiles {
+	t.Helper()
+	f := &keyFiles{dir: t.TempDir()}
+	var err error
+	if f.rsa, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		t.Fatal(err)
+	}
+	if _, f.ed, err = ed25519.GenerateKey(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	if f.retired, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(f.dir, "2026-01-01.pem"), "PUBLIC KEY", f.retired.Public())
+	writePEM(t, filepath.Join(f.dir, "2026-02-01.pem"), "PRIVATE KEY", f.rsa)
+	writePEM(t, filepath.Join(f.dir, "2026-03-01.pem"), "PRIVATE KEY", f.ed)
+	return f
+}
+
+func writePEM(t *testing.T, path, blockType string, key any) {
+	t.Helper()
+	var der []byte
+	var err error
+	if blockType == "PUBLIC KEY" {
+		der, err = x509.MarshalPKIXPublicKey(key)
+	} else {
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestKeyring(t *testing.T, jwtCfg config.JWT, secret string) (*Keyring, error) {
+	t.Helper()
+	return New(&config.Config{App: &config.App{Secret: secret}, JWT: &jwtCfg}, zap.NewNop())
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "1"})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestNew(t *testing.T) {
+	files := newKeyFiles(t)
+	tests := []struct {
+		name    string
+		jwt     config.JWT
+		secret  string
+		wantErr string
+		active  string
+	}{
+		{name: "no keys directory", secret: testSecret, wantErr: "JWT_KEYS_DIR"},
+		{name: "insecure hs256", jwt: config.JWT{InsecureHS256: true}, secret: testSecret},
+		{name: "insecure hs256 without secret", jwt: config.JWT{InsecureHS256: true}, wantErr: "APP_SECRET"},
+		{name: "insecure hs256 with short secret", jwt: config.JWT{InsecureHS256: true}, secret: testSecret[:31], wantErr: "APP_SECRET"},
+		{name: "keys without secret", jwt: config.JWT{KeysDir: files.dir}, active: "2026-03-01"},
+		{name: "accept hs256 without secret", jwt: config.JWT{KeysDir: files.dir, AcceptHS256: true}, wantErr: "APP_SECRET"},
+		{name: "accept hs256 with short secret", jwt: config.JWT{KeysDir: files.dir, AcceptHS256: true}, secret: testSecret[:31], wantErr: "APP_SECRET"},
+		{name: "last private key is active", jwt: config.JWT{KeysDir: files.dir}, secret: testSecret, active: "2026-03-01"},
+		{name: "configured active key", jwt: config.JWT{KeysDir: files.dir, ActiveKeyID: "2026-02-01"}, secret: testSecret, active: "2026-02-01"},
+		{name: "public key cannot be active", jwt: config.JWT{KeysDir: files.dir, ActiveKeyID: "2026-01-01"}, secret: testSecret, wantErr: "no private jwt key"},
+		{name: "unknown active key", jwt: config.JWT{KeysDir: files.dir, ActiveKeyID: "2027-01-01"}, secret: testSecret, wantErr: "no private jwt key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := newTestKeyring(t, tt.jwt, tt.secret)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("New() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if tt.active == "" {
+				if k.active != nil {
+					t.Errorf("active key = %s, want HS256 signing", k.active.ID)
+				}
+				return
+			}
+			if k.active == nil || k.active.ID != tt.active {
+				t.Errorf("active key = %v, want %s", k.active, tt.active)
+			}
+		})
+	}
+}
+
+func TestNewRejectsSmallRSAKey(t *testing.T) {
+	dir := t.TempDir()
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(dir, "small.pem"), "PRIVATE KEY", small)
+	if _, err := newTestKeyring(t, config.JWT{KeysDir: dir}, testSecret); err == nil {
+		t.Fatal("New() accepted a 1024 bit rsa key")
+	}
+}
+
+func TestSignUsesActiveKey(t *testing.T) {
+	files := newKeyFiles(t)
+	k, err := newTestKeyring(t, config.JWT{KeysDir: files.dir}, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := k.Sign(jwt.MapClaims{"sub": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Parse(signed, k.Keyfunc)
+	if err != nil {
+		t.Fatalf("parse own token: %v", err)
+	}
+	if token.Header["kid"] != "2026-03-01" || token.Method != jwt.SigningMethodEdDSA {
+		t.Errorf("token signed with kid %v and %s, want 2026-03-01 and EdDSA", token.Header["kid"], token.Method.Alg())
+	}
+}
+
+func TestKeyfunc(t *testing.T) {
+	files := newKeyFiles(t)
+	rsaPublicDER, err := x509.MarshalPKIXPublicKey(files.rsa.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPublicDER})
+	tests := []struct {
+		name        string
+		acceptHS256 bool
+		token       string
+		valid       bool
+	}{
+		{name: "rsa key", token: sign(t, jwt.SigningMethodRS256, "2026-02-01", files.rsa), valid: true},
+		{name: "ed25519 key", token: sign(t, jwt.SigningMethodEdDSA, "2026-03-01", files.ed), valid: true},
+		{name: "retired key", token: sign(t, jwt.SigningMethodRS256, "2026-01-01", files.retired), valid: true},
+		{name: "unknown kid", token: sign(t, jwt.SigningMethodRS256, "2027-01-01", files.rsa)},
+		{name: "kid of another key", token: sign(t, jwt.SigningMethodRS256, "2026-01-01", files.rsa)},
+		{name: "ed25519 token with rsa kid", token: sign(t, jwt.SigningMethodEdDSA, "2026-02-01", files.ed)},
+		{name: "rsa token with ed25519 kid", token: sign(t, jwt.SigningMethodRS256, "2026-03-01", files.rsa)},
+		{name: "hs256 with the public rsa key as secret", acceptHS256: true, token: sign(t, jwt.SigningMethodHS256, "2026-02-01", rsaPublicPEM)},
+		{name: "hs256 without kid", token: sign(t, jwt.SigningMethodHS256, "", []byte(testSecret))},
+		{name: "hs256 without kid while accepted", acceptHS256: true, token: sign(t, jwt.SigningMethodHS256, "", []byte(testSecret)), valid: true},
+		{name: "hs256 with another secret", acceptHS256: true, token: sign(t, jwt.SigningMethodHS256, "", []byte(strings.Repeat("x", 32)))},
+		{name: "hs512 without kid", acceptHS256: true, token: sign(t, jwt.SigningMethodHS512, "", []byte(testSecret))},
+		{name: "unsigned", acceptHS256: true, token: sign(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := newTestKeyring(t, config.JWT{KeysDir: files.dir, AcceptHS256: tt.acceptHS256}, testSecret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = jwt.Parse(tt.token, k.Keyfunc)
+			if tt.valid && err != nil {
+				t.Errorf("Parse() error = %v, want a valid token", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Parse() accepted the token")
+			}
+		})
+	}
+}
+
+func TestInsecureHS256(t *testing.T) {
+	k, err := newTestKeyring(t, config.JWT{InsecureHS256: true}, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := k.Sign(jwt.MapClaims{"sub": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Parse(signed, k.Keyfunc)
+	if err != nil {
+		t.Fatalf("parse own token: %v", err)
+	}
+	if token.Method != jwt.SigningMethodHS256 {
+		t.Errorf("token signed with %s, want HS256", token.Method.Alg())
+	}
+	if _, err := jwt.Parse(sign(t, jwt.SigningMethodHS256, "", []byte(strings.Repeat("x", 32))), k.Keyfunc); err == nil {
+		t.Error("Parse() accepted a token signed with another secret")
+	}
+	if jwks := k.JWKS(); len(jwks.Keys) != 0 {
+		t.Errorf("JWKS() = %+v, want no keys", jwks)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	files := newKeyFiles(t)
+	k, err := newTestKeyring(t, config.JWT{KeysDir: files.dir, AcceptHS256: true}, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := k.JWKS()
+	if len(jwks.Keys) != 3 {
+		t.Fatalf("JWKS() has %d keys, want 3", len(jwks.Keys))
+	}
+	want := []struct {
+		kid    string
+		alg    string
+		public crypto.PublicKey
+	}{
+		{kid: "2026-01-01", alg: "RS256", public: files.retired.Public()},
+		{kid: "2026-02-01", alg: "RS256", public: files.rsa.Public()},
+		{kid: "2026-03-01", alg: "EdDSA", public: files.ed.Public()},
+	}
+	for i, w := range want {
+		jwk := jwks.Keys[i]
+		if jwk.Kid != w.kid || jwk.Alg != w.alg || jwk.Use != "sig" {
+			t.Errorf("key %d = %s %s %s, want %s %s sig", i, jwk.Kid, jwk.Alg, jwk.Use, w.kid, w.alg)
+		}
+		switch public := w.public.(type) {
+		case *rsa.PublicKey:
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if jwk.Kty != "RSA" || errN != nil || errE != nil {
+				t.Fatalf("key %s = %+v, want an RSA key", jwk.Kid, jwk)
+			}
+			if new(big.Int).SetBytes(n).Cmp(public.N) != 0 || new(big.Int).SetBytes(e).Int64() != int64(public.E) {
+				t.Errorf("key %s does not hold the public rsa key", jwk.Kid)
+			}
+		case ed25519.PublicKey:
+			x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+			if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" || err != nil {
+				t.Fatalf("key %s = %+v, want an Ed25519 key", jwk.Kid, jwk)
+			}
+			if !public.Equal(ed25519.PublicKey(x)) {
+				t.Errorf("key %s does not hold the public ed25519 key", jwk.Kid)
+			}
+		}
+	}
+}
+
+func TestParseFor(t *testing.T) {
+	files := newKeyFiles(t)
+	k, err := newTestKeyring(t, config.JWT{KeysDir: files.dir}, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := jwt.NewNumericDate(time.Now().Add(time.Minute))
+	tests := []struct {
+		name    string
+		claims  jwt.RegisteredClaims
+		wantErr error
+	}{
+		{name: "token for the purpose", claims: jwt.RegisteredClaims{Subject: "7", Audience: jwt.ClaimStrings{"mfa"}, ExpiresAt: expires}},
+		{name: "token for another purpose", claims: jwt.RegisteredClaims{Subject: "7", Audience: jwt.ClaimStrings{"email_verification"}, ExpiresAt: expires}, wantErr: jwt.ErrTokenInvalidAudience},
+		{name: "access token without audience", claims: jwt.RegisteredClaims{Subject: "7", ExpiresAt: expires}, wantErr: jwt.ErrTokenRequiredClaimMissing},
+		{name: "token without expiry", claims: jwt.RegisteredClaims{Subject: "7", Audience: jwt.ClaimStrings{"mfa"}}, wantErr: jwt.ErrTokenRequiredClaimMissing},
+		{name: "expired token", claims: jwt.RegisteredClaims{Subject: "7", Audience: jwt.ClaimStrings{"mfa"}, ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}, wantErr: jwt.ErrTokenExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed, err := k.Sign(tt.claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var claims jwt.RegisteredClaims
+			err = k.ParseFor("mfa", signed, &claims)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseFor() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && claims.Subject != "7" {
+				t.Errorf("ParseFor() subject = %q, want 7", claims.Subject)
+			}
+		})
+	}
+	// a token of a key that is not in the keyring is rejected before its
+	// audience is looked at
+	other := sign(t, jwt.SigningMethodEdDSA, "2027-01-01", files.ed)
+	if err := k.ParseFor("mfa", other, &jwt.RegisteredClaims{}); !errors.Is(err, jwt.ErrTokenUnverifiable) {
+		t.Errorf("ParseFor() of an unknown key error = %v, want %v", err, jwt.ErrTokenUnverifiable)
+	}
+}
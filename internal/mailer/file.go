@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileMailer is a sink for development: it appends every email to a file, or
+// writes it to the log when no file is configured, instead of delivering it.
+type FileMailer struct {
+	mu        sync.Mutex
+	path      string
+	from      string
+	zapLogger *zap.Logger
+}
+
+func NewFileMailer(path, from string, zapLogger *zap.Logger) (*FileMailer, error) {
+	if path != "" {
+		// fail at startup rather than on the first email when the file can not be written
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		if err := file.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return &FileMailer{
+		path:      path,
+		from:      from,
+		zapLogger: zapLogger,
+	}, nil
+}
+
+func (f *FileMailer) Send(ctx context.Context, msg Message) error {
+	if f.path == "" {
+		f.zapLogger.Info("Email", zap.String("To", msg.To), zap.String("Subject", msg.Subject), zap.String("Body", msg.Body))
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	data := append(format(f.from, msg, time.Now()), "\r\n\r\n"...)
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
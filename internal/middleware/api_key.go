@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+// ScopedAuth accepts the access token of a session like JwtAuth, or an API key
+// that has the given scope. sessions can do everything their user can, an API
+// key only what its scopes allow.
+func ScopedAuth(userService domain.UserService, apiKeyService domain.APIKeyService, scope string, zapLogger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var ctx context.Context
+			var err error
+			if apiKey, ok := apiKeyFromRequest(r); ok {
+				ctx, err = authenticateAPIKey(r, apiKeyService, apiKey, scope)
+			} else {
+				ctx, err = authenticate(r, userService, zapLogger)
+			}
+			if err != nil {
+				writeAuthError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// apiKeyFromRequest returns the API key of a request, sent either in the
+// X-API-Key header or as a bearer token that starts with the API key prefix.
+func apiKeyFromRequest(r *http.Request) (string, bool) {
+	if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+		return apiKey, true
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "bearer") && strings.HasPrefix(token, model.APIKeyPrefix) {
+		return token, true
+	}
+	return "", false
+}
+
+// authenticateAPIKey returns the request context for the user of an API key.
+// requests made with an API key have no session, so they carry no jti.
+func authenticateAPIKey(r *http.Request, apiKeyService domain.APIKeyService, token, scope string) (context.Context, error) {
+	apiKey, user, err := apiKeyService.Authenticate(token)
+	if err != nil {
+		if errors.Is(err, domain.ErrAPIKeyInvalid) {
+			return nil, err
+		}
+		return nil, errTokenCheckFailed
+	}
+	if !apiKey.HasScope(scope) {
+		return nil, errScopeMissing
+	}
+	ctx := context.WithValue(r.Context(), "user_id", user.ID)
+	ctx = context.WithValue(ctx, "email", user.Email)
+	ctx = context.WithValue(ctx, "role", user.Role)
+	return ctx, nil
+}
@@ -15,7 +15,7 @@ func CorsMiddleware(cfg *config.Config) func(next http.Handler) http.Handler {
 			http.MethodPut,
 			http.MethodDelete,
 		},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	})
@@ -2,54 +2,147 @@ package middleware
 
 import (
 	"context"
+	"database/sql"
 	"errors"
-	"github.com/arshamroshannejad/task-rootext/config"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
 	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"net/http"
 	"strings"
 )
 
-func JwtAuth(redisDB *redis.Client, zapLogger *zap.Logger, cfg *config.Config) func(http.Handler) http.Handler {
+var (
+	errAuthHeaderMissing = errors.New("authorization header not provided")
+	errAuthHeaderInvalid = errors.New("invalid Authorization header")
+	errTokenExpired      = errors.New("token is expired")
+	errTokenInvalid      = errors.New("token is invalid")
+	errClaimsInvalid     = errors.New("invalid claims")
+	errTokenRevoked      = errors.New("token has been revoked")
+	errTokenCheckFailed  = errors.New("failed to check token")
+	errAPIKeyNotAllowed  = errors.New("api keys can not be used for this endpoint")
+	errScopeMissing      = errors.New("api key does not have the required scope")
+)
+
+// JwtAuth only accepts access tokens of a session. account endpoints, like
+// changing the password or managing API keys, use it so an API key can never
+// reach them.
+func JwtAuth(userService domain.UserService, zapLogger *zap.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				helpers.WriteJson(w, http.StatusUnauthorized, helpers.M{"error": "authorization header not provided"})
-				return
-			}
-			authToken := strings.Split(authHeader, " ")
-			if len(authToken) != 2 || strings.ToLower(authToken[0]) != "bearer" {
-				helpers.WriteJson(w, http.StatusUnauthorized, helpers.M{"error": "invalid Authorization header"})
+			if _, ok := apiKeyFromRequest(r); ok {
+				writeAuthError(w, errAPIKeyNotAllowed)
 				return
 			}
-			token, err := helpers.IsTokenValid(authToken[1], cfg.App.Secret)
+			ctx, err := authenticate(r, userService, zapLogger)
 			if err != nil {
-				switch {
-				case errors.Is(err, jwt.ErrTokenExpired):
-					helpers.WriteJson(w, http.StatusUnauthorized, helpers.M{"error": "token is expired"})
-				default:
-					zapLogger.Error("invalid token: failed to parse token", zap.Any("error", err))
-					helpers.WriteJson(w, http.StatusUnauthorized, helpers.M{"error": "token is invalid"})
-				}
+				writeAuthError(w, err)
 				return
 			}
-			if _, err := redisDB.Get(context.Background(), authHeader).Result(); err == nil {
-				helpers.WriteJson(w, http.StatusUnauthorized, helpers.M{"error": "token is expired"})
-				return
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// OptionalJwtAuth identifies the user like JwtAuth when the request carries a
+// valid token, or an API key with the read scope, but lets anonymous requests
+// and invalid credentials through, so public read endpoints can add
+// viewer-specific fields without requiring a login.
+func OptionalJwtAuth(userService domain.UserService, apiKeyService domain.APIKeyService, zapLogger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var ctx context.Context
+			var err error
+			if apiKey, ok := apiKeyFromRequest(r); ok {
+				ctx, err = authenticateAPIKey(r, apiKeyService, apiKey, model.ScopeRead)
+			} else {
+				ctx, err = authenticate(r, userService, zapLogger)
 			}
-			claims, err := helpers.GetClaims(token)
-			if err != nil {
-				zapLogger.Error("invalid claims: failed to parse token", zap.Any("error", err))
-				helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
-				return
+			if err == nil {
+				r = r.WithContext(ctx)
 			}
-			r = r.WithContext(context.WithValue(r.Context(), "user_id", claims["user_id"]))
-			r = r.WithContext(context.WithValue(r.Context(), "email", claims["email"]))
-			r = r.WithContext(context.WithValue(r.Context(), "exp", claims["exp"]))
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+func writeAuthError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errClaimsInvalid), errors.Is(err, errTokenCheckFailed):
+		helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+	case errors.Is(err, errAPIKeyNotAllowed), errors.Is(err, errScopeMissing):
+		helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": err.Error()})
+	default:
+		helpers.WriteJson(w, http.StatusUnauthorized, helpers.M{"error": err.Error()})
+	}
+}
+
+// authenticate validates the bearer token of a request and returns the request
+// context carrying its claims. tokens of revoked sessions and tokens issued
+// before the token version of the user was bumped, like by a password reset,
+// are rejected.
+func authenticate(r *http.Request, userService domain.UserService, zapLogger *zap.Logger) (context.Context, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, errAuthHeaderMissing
+	}
+	authToken := strings.Split(authHeader, " ")
+	if len(authToken) != 2 || strings.ToLower(authToken[0]) != "bearer" {
+		return nil, errAuthHeaderInvalid
+	}
+	token, err := userService.ParseAccessToken(authToken[1])
+	if err != nil {
+		switch {
+		case errors.Is(err, jwt.ErrTokenExpired):
+			return nil, errTokenExpired
+		default:
+			zapLogger.Error("invalid token: failed to parse token", zap.Any("error", err))
+			return nil, errTokenInvalid
+		}
+	}
+	claims, err := helpers.GetClaims(token)
+	if err != nil {
+		zapLogger.Error("invalid claims: failed to parse token", zap.Any("error", err))
+		return nil, errClaimsInvalid
+	}
+	userID, ok := claims["user_id"].(string)
+	if !ok {
+		return nil, errClaimsInvalid
+	}
+	// every token belongs to a session, tokens from before sessions existed are not accepted
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return nil, errTokenInvalid
+	}
+	revoked, err := userService.IsSessionRevoked(jti)
+	if err != nil {
+		return nil, errTokenCheckFailed
+	}
+	if revoked {
+		return nil, errTokenRevoked
+	}
+	// tokens issued before token versions existed have none, which is version 0
+	issuedVersion, _ := claims["tv"].(float64)
+	tokenVersion, err := userService.GetTokenVersion(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errTokenRevoked
+		}
+		return nil, errTokenCheckFailed
+	}
+	if int(issuedVersion) < tokenVersion {
+		return nil, errTokenRevoked
+	}
+	ctx := context.WithValue(r.Context(), "user_id", claims["user_id"])
+	ctx = context.WithValue(ctx, "email", claims["email"])
+	// tokens issued before roles existed have none, which is a regular user
+	role, ok := claims["role"].(string)
+	if !ok || role == "" {
+		role = model.RoleUser
+	}
+	ctx = context.WithValue(ctx, "role", role)
+	ctx = context.WithValue(ctx, "jti", jti)
+	ctx = context.WithValue(ctx, "exp", claims["exp"])
+	return ctx, nil
+}
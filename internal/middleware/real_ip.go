@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"github.com/arshamroshannejad/task-rootext/config"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIP sets the remote address of requests that come through a trusted proxy
+// to the client address the proxy forwarded. the headers of any other peer are
+// ignored, since clients can send whatever they like in them.
+func RealIP(cfg *config.Config) func(next http.Handler) http.Handler {
+	trusted := cfg.App.TrustedProxyNets
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip, ok := forwardedIP(r, trusted); ok {
+				r.RemoteAddr = ip
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func forwardedIP(r *http.Request, trusted []netip.Prefix) (string, bool) {
+	if len(trusted) == 0 {
+		return "", false
+	}
+	peer, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil || !isTrustedProxy(peer.Addr(), trusted) {
+		return "", false
+	}
+	if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		// each proxy appends the address it got the request from, so the
+		// rightmost address that is not a trusted proxy is the client. what is
+		// left of it came from the client and cannot be trusted.
+		hops := strings.Split(strings.Join(values, ","), ",")
+		var client netip.Addr
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				return "", false
+			}
+			client = addr.Unmap()
+			if !isTrustedProxy(client, trusted) {
+				break
+			}
+		}
+		return client.String(), true
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap().String(), true
+	}
+	return "", false
+}
+
+func isTrustedProxy(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
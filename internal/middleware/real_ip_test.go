@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/arshamroshannejad/task-rootext/config"
+)
+
+func TestRealIP(t *testing.T) {
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+	tests := []struct {
+		name       string
+		trusted    []netip.Prefix
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{name: "no trusted proxies", remoteAddr: "10.0.0.1:443", forwarded: []string{"198.51.100.7"}, want: "10.0.0.1:443"},
+		{name: "untrusted peer", trusted: proxies, remoteAddr: "203.0.113.9:443", forwarded: []string{"198.51.100.7"}, want: "203.0.113.9:443"},
+		{name: "trusted peer without headers", trusted: proxies, remoteAddr: "10.0.0.1:443", want: "10.0.0.1:443"},
+		{name: "single hop", trusted: proxies, remoteAddr: "10.0.0.1:443", forwarded: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "spoofed hops left of the client", trusted: proxies, remoteAddr: "10.0.0.1:443", forwarded: []string{"1.2.3.4, 198.51.100.7"}, want: "198.51.100.7"},
+		{name: "garbage left of the client", trusted: proxies, remoteAddr: "10.0.0.1:443", forwarded: []string{"not an ip, 198.51.100.7"}, want: "198.51.100.7"},
+		{name: "chain of trusted proxies", trusted: proxies, remoteAddr: "10.0.0.1:443", forwarded: []string{"1.2.3.4, 198.51.100.7, 10.0.0.3, 10.0.0.2"}, want: "198.51.100.7"},
+		{name: "hops in several headers", trusted: proxies, remoteAddr: "10.0.0.1:443", forwarded: []string{"1.2.3.4", "198.51.100.7, 10.0.0.2"}, want: "198.51.100.7"},
+		{name: "only trusted hops", trusted: proxies, remoteAddr: "10.0.0.1:443", forwarded: []string{"10.0.0.3, 10.0.0.2"}, want: "10.0.0.3"},
+		{name: "garbage right of the client", trusted: proxies, remoteAddr: "10.0.0.1:443", forwarded: []string{"198.51.100.7, not an ip"}, want: "10.0.0.1:443"},
+		{name: "ipv4 mapped hop", trusted: proxies, remoteAddr: "10.0.0.1:443", forwarded: []string{"::ffff:198.51.100.7"}, want: "198.51.100.7"},
+		{name: "ipv4 mapped trusted hop", trusted: proxies, remoteAddr: "10.0.0.1:443", forwarded: []string{"198.51.100.7, ::ffff:10.0.0.2"}, want: "198.51.100.7"},
+		{name: "ipv6 trusted peer", trusted: proxies, remoteAddr: "[::1]:443", forwarded: []string{"2001:db8::7"}, want: "2001:db8::7"},
+		{name: "ipv4 mapped trusted peer", trusted: proxies, remoteAddr: "[::ffff:10.0.0.1]:443", forwarded: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "x-real-ip from trusted peer", trusted: proxies, remoteAddr: "10.0.0.1:443", realIP: "198.51.100.7", want: "198.51.100.7"},
+		{name: "x-real-ip from untrusted peer", trusted: proxies, remoteAddr: "203.0.113.9:443", realIP: "198.51.100.7", want: "203.0.113.9:443"},
+		{name: "invalid x-real-ip", trusted: proxies, remoteAddr: "10.0.0.1:443", realIP: "not an ip", want: "10.0.0.1:443"},
+		{name: "x-forwarded-for before x-real-ip", trusted: proxies, remoteAddr: "10.0.0.1:443", forwarded: []string{"198.51.100.7"}, realIP: "1.2.3.4", want: "198.51.100.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{App: &config.App{TrustedProxyNets: tt.trusted}}
+			var got string
+			handler := RealIP(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
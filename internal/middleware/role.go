@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"net/http"
+	"slices"
+)
+
+// RequireRole rejects users whose role is not one of the given roles. it has
+// to run after JwtAuth.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.Contains(roles, helpers.GetUserRole(r)) {
+				helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": http.StatusText(http.StatusForbidden)})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/arshamroshannejad/task-rootext/config"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"net/http"
+)
+
+// RequireVerifiedEmail rejects users that have not verified their email
+// address yet, unless auth.RequireVerifiedEmail is turned off. it has to run
+// after JwtAuth.
+func RequireVerifiedEmail(userService domain.UserService, cfg *config.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !cfg.Auth.RequireVerifiedEmail {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := userService.GetUserByID(helpers.GetUserID(r))
+			if err != nil {
+				helpers.WriteJson(w, http.StatusInternalServerError, helpers.M{"error": http.StatusText(http.StatusInternalServerError)})
+				return
+			}
+			if !user.IsEmailVerified() {
+				helpers.WriteJson(w, http.StatusForbidden, helpers.M{"error": "email address is not verified"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
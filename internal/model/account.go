@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// UserExport is the personal data of a user returned by the data export.
+type UserExport struct {
+	ExportedAt   time.Time     `json:"exported_at" example:"2023-10-27T12:00:00Z"`
+	Profile      User          `json:"profile"`
+	Posts        []Post        `json:"posts"`
+	Comments     []Comment     `json:"comments"`
+	PostVotes    []PostVote    `json:"post_votes"`
+	CommentVotes []CommentVote `json:"comment_votes"`
+}
+
+// PostVote is a vote a user cast on a post.
+type PostVote struct {
+	PostID    string     `json:"post_id" example:"1"`
+	Vote      int        `json:"vote" example:"1"`
+	CreatedAt *time.Time `json:"created_at" example:"2023-10-27T10:15:00Z"`
+}
+
+// CommentVote is a vote a user cast on a comment.
+type CommentVote struct {
+	CommentID string `json:"comment_id" example:"4"`
+	Vote      int    `json:"vote" example:"-1"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// scopes an API key can be limited to. requests with an access token of a
+// session have all of them.
+const (
+	ScopeRead = "read"
+	ScopePost = "post"
+	ScopeVote = "vote"
+)
+
+// APIKeyPrefix starts every API key, so they are told apart from access tokens.
+const APIKeyPrefix = "pat_"
+
+type APIKey struct {
+	ID         string     `json:"id" example:"4"`
+	UserID     string     `json:"-"`
+	Name       string     `json:"name" example:"nightly digest bot"`
+	Prefix     string     `json:"prefix" example:"pat_Zm9vYmFy"`
+	Scopes     []string   `json:"scopes" example:"read,post"`
+	ExpiresAt  *time.Time `json:"expires_at" example:"2030-01-01T00:00:00Z"`
+	LastUsedAt *time.Time `json:"last_used_at" example:"2023-10-27T12:30:00Z"`
+	CreatedAt  time.Time  `json:"created_at" example:"2023-10-27T10:00:00Z"`
+}
+
+// IsExpired reports whether the key has an expiry that has passed.
+func (a *APIKey) IsExpired() bool {
+	return a.ExpiresAt != nil && !a.ExpiresAt.After(time.Now())
+}
+
+func (a *APIKey) HasScope(scope string) bool {
+	return slices.Contains(a.Scopes, scope)
+}
+
+// CreatedAPIKey is a new API key with its token, which is only shown once.
+type CreatedAPIKey struct {
+	APIKey
+	Token string `json:"token" example:"pat_Zm9vYmFyYmF6cXV4cXV1eGNvcmdlZ3JhdWx0Z2FycGx5"`
+}
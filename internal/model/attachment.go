@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+type Attachment struct {
+	ID          string    `json:"id" example:"1"`
+	PostID      string    `json:"post_id" example:"1"`
+	URL         string    `json:"url" example:"http://localhost:8000/uploads/posts/1/3f2a9c0d1b7e4a65b2c8d9e0f1a2b3c4"`
+	Filename    string    `json:"filename" example:"diagram.png"`
+	ContentType string    `json:"content_type" example:"image/png"`
+	Size        int64     `json:"size" example:"48213"`
+	CreatedAt   time.Time `json:"created_at" example:"2023-10-27T10:00:00Z"`
+}
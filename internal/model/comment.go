@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+type Comment struct {
+	ID        string    `json:"id" example:"1"`
+	PostID    string    `json:"post_id" example:"1"`
+	ParentID  *string   `json:"parent_id" example:"4"`
+	UserID    string    `json:"user_id" example:"123"`
+	Text      string    `json:"text" example:"Nice post!"`
+	CreatedAt time.Time `json:"created_at" example:"2023-10-27T10:00:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2023-10-27T10:30:00Z"`
+	VoteCount int       `json:"vote_count" example:"12"`
+	Replies   []Comment `json:"replies"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type Community struct {
+	ID          string    `json:"id" example:"1"`
+	Name        string    `json:"name" example:"golang"`
+	Description string    `json:"description" example:"Everything about the Go programming language."`
+	CreatorID   string    `json:"creator_id" example:"123"`
+	CreatedAt   time.Time `json:"created_at" example:"2023-10-27T10:00:00Z"`
+	Moderators  []string  `json:"moderators" example:"123,456"`
+}
@@ -2,12 +2,51 @@ package model
 
 import "time"
 
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+)
+
 type Post struct {
-	ID        string    `json:"id" example:"1"`
-	Title     string    `json:"title" example:"My First Post"`
-	Text      string    `json:"text" example:"This is the content of my first post."`
-	CreatedAt time.Time `json:"created_at" example:"2023-10-27T10:00:00Z"`
-	UpdatedAt time.Time `json:"updated_at" example:"2023-10-27T10:30:00Z"`
-	UserID    string    `json:"user_id" example:"123"`
-	VoteCount int       `json:"vote_count" example:"100"`
+	ID          string       `json:"id" example:"1"`
+	Title       string       `json:"title" example:"My First Post"`
+	Text        string       `json:"text" example:"This is the content of my first post."`
+	CreatedAt   time.Time    `json:"created_at" example:"2023-10-27T10:00:00Z"`
+	UpdatedAt   time.Time    `json:"updated_at" example:"2023-10-27T10:30:00Z"`
+	Edited      bool         `json:"edited" example:"true"`
+	EditedAt    *time.Time   `json:"edited_at" example:"2023-10-27T10:30:00Z"`
+	UserID      string       `json:"user_id" example:"123"`
+	CommunityID *string      `json:"community_id" example:"1"`
+	Status      string       `json:"status" example:"published"`
+	PublishAt   *time.Time   `json:"publish_at" example:"2023-10-27T10:00:00Z"`
+	Tags        []string     `json:"tags" example:"golang,redis"`
+	Attachments []Attachment `json:"attachments"`
+	Upvotes     int          `json:"upvotes" example:"120"`
+	Downvotes   int          `json:"downvotes" example:"20"`
+	VoteCount   int          `json:"vote_count" example:"100"`
+	// Saved, MyVote and IsOwner are only set when the request is made by a
+	// logged-in user. MyVote is 1, -1, or 0 when the user has not voted.
+	Saved   *bool `json:"saved,omitempty" example:"false"`
+	MyVote  *int  `json:"my_vote,omitempty" example:"1"`
+	IsOwner *bool `json:"is_owner,omitempty" example:"false"`
+}
+
+func (p *Post) IsPublished() bool {
+	return p.Status == PostStatusPublished
+}
+
+// PostViewerState is what a single user has done with a post.
+type PostViewerState struct {
+	Saved  bool
+	MyVote int
+}
+
+type PostSearchResult struct {
+	Post
+	Rank float64 `json:"rank" example:"0.6079271"`
+	// the html escaped title, the matches are wrapped in <mark> tags
+	TitleHighlight string `json:"title_highlight" example:"My First <mark>Post</mark> &amp; more"`
+	// up to two html escaped fragments of the text, the matches are wrapped in <mark> tags
+	Snippet string `json:"snippet" example:"This is the content of my first <mark>post</mark>."`
 }
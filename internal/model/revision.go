@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+type PostRevision struct {
+	PostID    string    `json:"post_id" example:"1"`
+	Revision  int       `json:"revision" example:"2"`
+	Title     string    `json:"title" example:"My First Post"`
+	Text      string    `json:"text" example:"This is the edited content of my first post."`
+	CreatedAt time.Time `json:"created_at" example:"2023-10-27T10:30:00Z"`
+}
+
+type DiffLine struct {
+	Op   string `json:"op" example:"insert" enums:"equal,insert,delete"`
+	Text string `json:"text" example:"This is the edited content of my first post."`
+}
+
+type PostRevisionDiff struct {
+	PostRevision
+	PreviousRevision *int       `json:"previous_revision" example:"1"`
+	TitleDiff        []DiffLine `json:"title_diff"`
+	TextDiff         []DiffLine `json:"text_diff"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+type Session struct {
+	ID         string    `json:"id" example:"7"`
+	JTI        string    `json:"-"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0"`
+	IP         string    `json:"ip" example:"203.0.113.7"`
+	CreatedAt  time.Time `json:"created_at" example:"2023-10-27T10:00:00Z"`
+	LastSeenAt time.Time `json:"last_seen_at" example:"2023-10-27T12:30:00Z"`
+	// Current marks the session the request was made with.
+	Current bool `json:"current" example:"true"`
+}
@@ -0,0 +1,6 @@
+package model
+
+type Tag struct {
+	Name      string `json:"name" example:"golang"`
+	PostCount int    `json:"post_count" example:"42"`
+}
@@ -0,0 +1,105 @@
+// Package policy decides who may act on posts, comments and communities, so
+// handlers ask it instead of comparing owner ids themselves.
+package policy
+
+import (
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"net/http"
+)
+
+// Actor is the user a request is made by.
+type Actor struct {
+	UserID string
+	Role   string
+}
+
+// ActorFromRequest returns the actor of a request that passed JwtAuth.
+func ActorFromRequest(r *http.Request) Actor {
+	return Actor{UserID: helpers.GetUserID(r), Role: helpers.GetUserRole(r)}
+}
+
+func (a Actor) IsAdmin() bool {
+	return a.Role == model.RoleAdmin
+}
+
+// IsStaff reports whether the actor moderates the whole site.
+func (a Actor) IsStaff() bool {
+	return a.Role == model.RoleAdmin || a.Role == model.RoleModerator
+}
+
+type Policy struct {
+	communityService domain.CommunityService
+}
+
+func New(communityService domain.CommunityService) *Policy {
+	return &Policy{communityService: communityService}
+}
+
+// CanEditPost allows the author, admins and moderators to edit a post.
+func (p *Policy) CanEditPost(actor Actor, post *model.Post) bool {
+	return post.UserID == actor.UserID || actor.IsStaff()
+}
+
+// CanPublishPost allows only the author to publish a draft.
+func (p *Policy) CanPublishPost(actor Actor, post *model.Post) bool {
+	return post.UserID == actor.UserID
+}
+
+// CanDeletePost allows the author, admins, moderators and the moderators of
+// the post community to delete a post.
+func (p *Policy) CanDeletePost(actor Actor, post *model.Post) (bool, error) {
+	if p.CanEditPost(actor, post) {
+		return true, nil
+	}
+	if post.CommunityID == nil {
+		return false, nil
+	}
+	return p.communityService.IsCommunityModerator(*post.CommunityID, actor.UserID)
+}
+
+// CanRestorePost allows the author, admins and moderators to restore a deleted post.
+func (p *Policy) CanRestorePost(actor Actor, post *model.Post) bool {
+	return post.UserID == actor.UserID || actor.IsStaff()
+}
+
+// CanManageAttachments allows whoever can edit a post to add or remove its attachments.
+func (p *Policy) CanManageAttachments(actor Actor, post *model.Post) bool {
+	return p.CanEditPost(actor, post)
+}
+
+// CanVotePost allows everyone but the author to vote on a post.
+func (p *Policy) CanVotePost(actor Actor, post *model.Post) bool {
+	return post.UserID != actor.UserID
+}
+
+// CanEditComment allows the author, admins and moderators to edit a comment.
+func (p *Policy) CanEditComment(actor Actor, comment *model.Comment) bool {
+	return comment.UserID == actor.UserID || actor.IsStaff()
+}
+
+// CanDeleteComment allows the author, admins and moderators to delete a comment.
+func (p *Policy) CanDeleteComment(actor Actor, comment *model.Comment) bool {
+	return comment.UserID == actor.UserID || actor.IsStaff()
+}
+
+// CanVoteComment allows everyone but the author to vote on a comment.
+func (p *Policy) CanVoteComment(actor Actor, comment *model.Comment) bool {
+	return comment.UserID != actor.UserID
+}
+
+// CanModerateCommunity allows admins, moderators and the moderators of the
+// community to update it.
+func (p *Policy) CanModerateCommunity(actor Actor, communityID string) (bool, error) {
+	if actor.IsStaff() {
+		return true, nil
+	}
+	return p.communityService.IsCommunityModerator(communityID, actor.UserID)
+}
+
+// CanManageModerators allows the creator of the community and admins to add or
+// remove its moderators.
+func (p *Policy) CanManageModerators(actor Actor, community *model.Community) bool {
+	return community.CreatorID == actor.UserID || actor.IsAdmin()
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"github.com/lib/pq"
+	"time"
+)
+
+type accountRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewAccountRepository(db *sql.DB) domain.AccountRepository {
+	return &accountRepositoryImpl{
+		db: db,
+	}
+}
+
+// GetPosts returns every post of a user that was not purged yet, whatever its
+// status, oldest first.
+func (a *accountRepositoryImpl) GetPosts(userID string) ([]model.Post, error) {
+	query := `
+			SELECT
+				` + postSelectColumns + `
+			FROM
+				posts p
+			WHERE
+				p.user_id = $1
+			ORDER BY
+				p.id
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	rows, err := a.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts := []model.Post{}
+	for rows.Next() {
+		var post model.Post
+		err := rows.Scan(
+			&post.ID,
+			&post.Title,
+			&post.Text,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&post.EditedAt,
+			&post.UserID,
+			&post.CommunityID,
+			&post.Status,
+			&post.PublishAt,
+			pq.Array(&post.Tags),
+			jsonColumn(&post.Attachments),
+			&post.Upvotes,
+			&post.Downvotes,
+			&post.VoteCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		post.Edited = post.EditedAt != nil
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+// GetComments returns every comment of a user as a flat list, oldest first.
+func (a *accountRepositoryImpl) GetComments(userID string) ([]model.Comment, error) {
+	query := `
+			SELECT
+				c.id,
+				c.post_id,
+				c.parent_id,
+				c.user_id,
+				c.text,
+				c.created_at,
+				c.updated_at,
+				(SELECT COALESCE(SUM(v.vote), 0) FROM comment_votes v WHERE v.comment_id = c.id) AS vote_count
+			FROM
+				comments c
+			WHERE
+				c.user_id = $1
+			ORDER BY
+				c.id
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	rows, err := a.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	comments := []model.Comment{}
+	for rows.Next() {
+		var comment model.Comment
+		err := rows.Scan(
+			&comment.ID,
+			&comment.PostID,
+			&comment.ParentID,
+			&comment.UserID,
+			&comment.Text,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+			&comment.VoteCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		comment.Replies = []model.Comment{}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+func (a *accountRepositoryImpl) GetPostVotes(userID string) ([]model.PostVote, error) {
+	query := "SELECT post_id, vote, created_at FROM votes WHERE user_id = $1 ORDER BY post_id"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	rows, err := a.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	votes := []model.PostVote{}
+	for rows.Next() {
+		var vote model.PostVote
+		if err := rows.Scan(&vote.PostID, &vote.Vote, &vote.CreatedAt); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+	return votes, rows.Err()
+}
+
+func (a *accountRepositoryImpl) GetCommentVotes(userID string) ([]model.CommentVote, error) {
+	query := "SELECT comment_id, vote FROM comment_votes WHERE user_id = $1 ORDER BY comment_id"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	rows, err := a.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	votes := []model.CommentVote{}
+	for rows.Next() {
+		var vote model.CommentVote
+		if err := rows.Scan(&vote.CommentID, &vote.Vote); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+	return votes, rows.Err()
+}
+
+// Anonymize deletes an account. the user row stays behind without any personal
+// data, so published posts and comments keep a valid author, while drafts,
+// votes, saved posts, moderator seats, sessions and every kind of token go away.
+// the token version is bumped and returned, so no access token of the user is
+// accepted anymore. it returns sql.ErrNoRows when the account is already deleted.
+func (a *accountRepositoryImpl) Anonymize(userID string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	// votes on posts are also counted on the posts, take them off the counters
+	query := `
+                WITH removed AS (
+                    DELETE FROM votes WHERE user_id = $1 RETURNING post_id, vote
+                ), c AS (
+                    SELECT
+                        post_id,
+                        COUNT(*) FILTER (WHERE vote = 1)::INTEGER AS upvotes,
+                        COUNT(*) FILTER (WHERE vote = -1)::INTEGER AS downvotes
+                    FROM removed
+                    GROUP BY post_id
+                )
+                UPDATE posts p
+                SET upvotes = p.upvotes - c.upvotes,
+                    downvotes = p.downvotes - c.downvotes,
+                    score = (p.upvotes - c.upvotes) - (p.downvotes - c.downvotes),
+                    hot_score = hot_score(p.upvotes - c.upvotes, p.downvotes - c.downvotes, COALESCE(p.publish_at, p.created_at)),
+                    best_score = best_score(p.upvotes - c.upvotes, p.downvotes - c.downvotes),
+                    controversial_score = controversial_score(p.upvotes - c.upvotes, p.downvotes - c.downvotes)
+                FROM c
+                WHERE p.id = c.post_id
+        `
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return 0, err
+	}
+	owned := []string{
+		"DELETE FROM comment_votes WHERE user_id = $1",
+		"DELETE FROM saved_posts WHERE user_id = $1",
+		"DELETE FROM community_moderators WHERE user_id = $1",
+		"DELETE FROM posts WHERE user_id = $1 AND status <> 'published'",
+		"DELETE FROM refresh_tokens WHERE user_id = $1",
+		"DELETE FROM sessions WHERE user_id = $1",
+		"DELETE FROM password_reset_tokens WHERE user_id = $1",
+		"DELETE FROM recovery_codes WHERE user_id = $1",
+		"DELETE FROM api_keys WHERE user_id = $1",
+	}
+	for _, query := range owned {
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return 0, err
+		}
+	}
+	// neither the email nor the username of a deleted account passes validation,
+	// so nobody can register them and they never collide
+	query = `
+                UPDATE users
+                SET email = 'deleted:' || id,
+                    username = 'deleted_' || id,
+                    display_name = '',
+                    bio = '',
+                    password = '',
+                    email_verified_at = NULL,
+                    role = 'user',
+                    totp_secret = NULL,
+                    totp_enabled_at = NULL,
+                    token_version = token_version + 1,
+                    deleted_at = CURRENT_TIMESTAMP
+                WHERE id = $1 AND deleted_at IS NULL
+                RETURNING token_version
+        `
+	var tokenVersion int
+	if err := tx.QueryRowContext(ctx, query, userID).Scan(&tokenVersion); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return tokenVersion, nil
+}
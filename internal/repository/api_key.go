@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"github.com/lib/pq"
+	"time"
+)
+
+type apiKeyRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) domain.APIKeyRepository {
+	return &apiKeyRepositoryImpl{
+		db: db,
+	}
+}
+
+func (a *apiKeyRepositoryImpl) GetByUser(userID string) (*[]model.APIKey, error) {
+	query := `
+                SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+                FROM api_keys
+                WHERE user_id = $1
+                ORDER BY created_at DESC, id DESC
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	rows, err := a.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	apiKeys := []model.APIKey{}
+	for rows.Next() {
+		var apiKey model.APIKey
+		err := rows.Scan(
+			&apiKey.ID,
+			&apiKey.UserID,
+			&apiKey.Name,
+			&apiKey.Prefix,
+			pq.Array(&apiKey.Scopes),
+			&apiKey.ExpiresAt,
+			&apiKey.LastUsedAt,
+			&apiKey.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &apiKeys, nil
+}
+
+func (a *apiKeyRepositoryImpl) GetByID(userID, keyID string) (*model.APIKey, error) {
+	query := `
+                SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+                FROM api_keys
+                WHERE id = $1 AND user_id = $2
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	row := a.db.QueryRowContext(ctx, query, keyID, userID)
+	return collectAPIKeyRow(row)
+}
+
+func (a *apiKeyRepositoryImpl) GetByHash(tokenHash string) (*model.APIKey, error) {
+	query := `
+                SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+                FROM api_keys
+                WHERE token_hash = $1
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	row := a.db.QueryRowContext(ctx, query, tokenHash)
+	return collectAPIKeyRow(row)
+}
+
+func (a *apiKeyRepositoryImpl) Create(userID, name, prefix, tokenHash string, scopes []string, expiresAt *time.Time) (*model.APIKey, error) {
+	query := `
+                INSERT INTO api_keys (user_id, name, prefix, token_hash, scopes, expires_at)
+                VALUES ($1, $2, $3, $4, $5, $6)
+                RETURNING id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	row := a.db.QueryRowContext(ctx, query, userID, name, prefix, tokenHash, pq.Array(scopes), expiresAt)
+	return collectAPIKeyRow(row)
+}
+
+func (a *apiKeyRepositoryImpl) Update(userID, keyID, name string, scopes []string) (*model.APIKey, error) {
+	query := `
+                UPDATE api_keys SET name = $3, scopes = $4
+                WHERE id = $1 AND user_id = $2
+                RETURNING id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	row := a.db.QueryRowContext(ctx, query, keyID, userID, name, pq.Array(scopes))
+	return collectAPIKeyRow(row)
+}
+
+// Delete removes an API key of a user. it returns sql.ErrNoRows when the user
+// has no key with the id.
+func (a *apiKeyRepositoryImpl) Delete(userID, keyID string) error {
+	query := "DELETE FROM api_keys WHERE id = $1 AND user_id = $2"
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	result, err := a.db.ExecContext(ctx, query, keyID, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Touch records that a key was used. it writes at most once a minute per key,
+// so bots making many requests do not turn every request into a write.
+func (a *apiKeyRepositoryImpl) Touch(keyID string) error {
+	query := `
+                UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP
+                WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := a.db.ExecContext(ctx, query, keyID)
+	return err
+}
+
+func collectAPIKeyRow(row *sql.Row) (*model.APIKey, error) {
+	var apiKey model.APIKey
+	err := row.Scan(
+		&apiKey.ID,
+		&apiKey.UserID,
+		&apiKey.Name,
+		&apiKey.Prefix,
+		pq.Array(&apiKey.Scopes),
+		&apiKey.ExpiresAt,
+		&apiKey.LastUsedAt,
+		&apiKey.CreatedAt,
+	)
+	return &apiKey, err
+}
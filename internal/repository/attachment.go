@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"github.com/lib/pq"
+	"time"
+)
+
+type attachmentRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewAttachmentRepository(db *sql.DB) domain.AttachmentRepository {
+	return &attachmentRepositoryImpl{
+		db: db,
+	}
+}
+
+func (a *attachmentRepositoryImpl) GetByID(attachmentID string) (*model.Attachment, error) {
+	query := `
+                SELECT id, post_id, url, filename, content_type, size, created_at
+                FROM attachments
+                WHERE id = $1 AND post_id IS NOT NULL
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	row := a.db.QueryRowContext(ctx, query, attachmentID)
+	return collectAttachmentRow(row)
+}
+
+func (a *attachmentRepositoryImpl) CountByPost(postID string) (int, error) {
+	query := "SELECT COUNT(*) FROM attachments WHERE post_id = $1"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	var count int
+	err := a.db.QueryRowContext(ctx, query, postID).Scan(&count)
+	return count, err
+}
+
+// Create adds an attachment to a post that has less than maxPerPost of them. it
+// returns sql.ErrNoRows when the post is full or does not exist. the row lock of
+// the post makes concurrent uploads to it count one after another.
+func (a *attachmentRepositoryImpl) Create(attachment *model.Attachment, userID, storageKey string, maxPerPost int) (*model.Attachment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var postID string
+	query := "SELECT id FROM posts WHERE id = $1 FOR UPDATE"
+	if err := tx.QueryRowContext(ctx, query, attachment.PostID).Scan(&postID); err != nil {
+		return nil, err
+	}
+	var count int
+	query = "SELECT COUNT(*) FROM attachments WHERE post_id = $1"
+	if err := tx.QueryRowContext(ctx, query, postID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count >= maxPerPost {
+		return nil, sql.ErrNoRows
+	}
+	query = `
+                INSERT INTO attachments (post_id, user_id, storage_key, url, filename, content_type, size) 
+                VALUES ($1, $2, $3, $4, $5, $6, $7) 
+                RETURNING id, post_id, url, filename, content_type, size, created_at
+        `
+	args := []any{
+		postID, userID, storageKey, attachment.URL, attachment.Filename, attachment.ContentType, attachment.Size,
+	}
+	createdAttachment, err := collectAttachmentRow(tx.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return createdAttachment, nil
+}
+
+func (a *attachmentRepositoryImpl) Detach(attachmentID string) error {
+	query := "UPDATE attachments SET post_id = NULL WHERE id = $1"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	_, err := a.db.ExecContext(ctx, query, attachmentID)
+	return err
+}
+
+func (a *attachmentRepositoryImpl) GetDetachedKeys(limit int) (map[string]string, error) {
+	query := "SELECT id, storage_key FROM attachments WHERE post_id IS NULL ORDER BY id LIMIT $1"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	rows, err := a.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := make(map[string]string)
+	for rows.Next() {
+		var attachmentID, storageKey string
+		if err := rows.Scan(&attachmentID, &storageKey); err != nil {
+			return nil, err
+		}
+		keys[attachmentID] = storageKey
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (a *attachmentRepositoryImpl) DeleteDetached(attachmentIDs []string) error {
+	query := "DELETE FROM attachments WHERE id = ANY($1::int[]) AND post_id IS NULL"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	_, err := a.db.ExecContext(ctx, query, pq.Array(attachmentIDs))
+	return err
+}
+
+func collectAttachmentRow(row *sql.Row) (*model.Attachment, error) {
+	var attachment model.Attachment
+	err := row.Scan(
+		&attachment.ID,
+		&attachment.PostID,
+		&attachment.URL,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/entities"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"github.com/lib/pq"
+	"time"
+)
+
+type commentRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewCommentRepository(db *sql.DB) domain.CommentRepository {
+	return &commentRepositoryImpl{
+		db: db,
+	}
+}
+
+func (c *commentRepositoryImpl) GetAll(postID string, filter *helpers.PaginateFilter) (*[]model.Comment, helpers.Metadata, error) {
+	query := fmt.Sprintf(
+		`
+			SELECT
+				COUNT(*) OVER() AS total_records,
+				c.id,
+				c.post_id,
+				c.parent_id,
+				c.user_id,
+				c.text,
+				c.created_at,
+				c.updated_at,
+				COALESCE(SUM(v.vote), 0) AS vote_count
+			FROM
+				comments c
+			LEFT JOIN
+				comment_votes v ON c.id = v.comment_id
+			WHERE
+				c.post_id = $1 AND c.parent_id IS NULL
+			GROUP BY
+				c.id
+			ORDER BY
+				%s %s, c.id
+			LIMIT
+				$2
+			OFFSET
+				$3;
+        `,
+		filter.SortValue(),
+		filter.SortDirection(),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	rows, err := c.db.QueryContext(ctx, query, postID, filter.Limit(), filter.OffSet())
+	if err != nil {
+		return nil, helpers.Metadata{}, err
+	}
+	defer rows.Close()
+	var comments []model.Comment
+	var totalRecords int
+	for rows.Next() {
+		var comment model.Comment
+		err := rows.Scan(
+			&totalRecords,
+			&comment.ID,
+			&comment.PostID,
+			&comment.ParentID,
+			&comment.UserID,
+			&comment.Text,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+			&comment.VoteCount,
+		)
+		if err != nil {
+			return nil, helpers.Metadata{}, err
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, helpers.Metadata{}, err
+	}
+	metadata := helpers.CalculateMetadata(totalRecords, filter.Page, filter.PageSize)
+	return &comments, metadata, nil
+}
+
+func (c *commentRepositoryImpl) GetReplies(parentIDs []string, filter *helpers.PaginateFilter) (*[]model.Comment, error) {
+	query := fmt.Sprintf(
+		`
+			WITH RECURSIVE tree AS (
+				SELECT * FROM comments WHERE parent_id = ANY($1::int[])
+				UNION ALL
+				SELECT c.* FROM comments c JOIN tree t ON c.parent_id = t.id
+			)
+			SELECT
+				t.id,
+				t.post_id,
+				t.parent_id,
+				t.user_id,
+				t.text,
+				t.created_at,
+				t.updated_at,
+				COALESCE(SUM(v.vote), 0) AS vote_count
+			FROM
+				tree t
+			LEFT JOIN
+				comment_votes v ON t.id = v.comment_id
+			GROUP BY
+				t.id, t.post_id, t.parent_id, t.user_id, t.text, t.created_at, t.updated_at
+			ORDER BY
+				%s %s, t.id;
+        `,
+		filter.SortValue(),
+		filter.SortDirection(),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	rows, err := c.db.QueryContext(ctx, query, pq.Array(parentIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var comments []model.Comment
+	for rows.Next() {
+		var comment model.Comment
+		err := rows.Scan(
+			&comment.ID,
+			&comment.PostID,
+			&comment.ParentID,
+			&comment.UserID,
+			&comment.Text,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+			&comment.VoteCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &comments, nil
+}
+
+func (c *commentRepositoryImpl) GetByID(commentID string) (*model.Comment, error) {
+	query := `
+			SELECT
+				c.id,
+				c.post_id,
+				c.parent_id,
+				c.user_id,
+				c.text,
+				c.created_at,
+				c.updated_at,
+				COALESCE(SUM(v.vote), 0) as vote_count
+			FROM comments c
+			LEFT JOIN
+			    comment_votes v ON c.id = v.comment_id
+			WHERE
+			    c.id = $1
+			GROUP BY
+			    c.id
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	row := c.db.QueryRowContext(ctx, query, commentID)
+	return collectCommentRow(row)
+}
+
+func (c *commentRepositoryImpl) Create(comment *entities.CommentCreateRequest, postID, userID string) (*model.Comment, error) {
+	query := `
+                INSERT INTO comments (post_id, parent_id, user_id, text)
+                VALUES ($1, $2, $3, $4)
+                RETURNING id, post_id, parent_id, user_id, text, created_at, updated_at, 0 as vote_count
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	args := []any{postID, comment.ParentID, userID, comment.Text}
+	row := c.db.QueryRowContext(ctx, query, args...)
+	if row.Err() != nil {
+		return nil, row.Err()
+	}
+	return collectCommentRow(row)
+}
+
+func (c *commentRepositoryImpl) Update(comment *entities.CommentUpdateRequest, commentID string) (*model.Comment, error) {
+	query := `
+                UPDATE comments
+                SET text = $1, updated_at = CURRENT_TIMESTAMP
+                WHERE id = $2
+                RETURNING id, post_id, parent_id, user_id, text, created_at, updated_at,
+                    (SELECT COALESCE(SUM(vote), 0) FROM comment_votes WHERE comment_id = $2) as vote_count
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	args := []any{comment.Text, commentID}
+	row := c.db.QueryRowContext(ctx, query, args...)
+	if row.Err() != nil {
+		return nil, row.Err()
+	}
+	return collectCommentRow(row)
+}
+
+func (c *commentRepositoryImpl) Delete(commentID string) error {
+	query := "DELETE FROM comments WHERE id = $1"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	_, err := c.db.ExecContext(ctx, query, commentID)
+	return err
+}
+
+func (c *commentRepositoryImpl) AddVote(commentID, userID, vote string) error {
+	query := "INSERT INTO comment_votes (user_id, comment_id, vote) VALUES ($1, $2, $3) ON CONFLICT (user_id, comment_id) DO UPDATE SET vote = $3"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	args := []any{userID, commentID, vote}
+	_, err := c.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (c *commentRepositoryImpl) RemoveVote(commentID, userID string) error {
+	query := "DELETE FROM comment_votes WHERE user_id = $1 AND comment_id = $2"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	args := []any{userID, commentID}
+	_, err := c.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func collectCommentRow(row *sql.Row) (*model.Comment, error) {
+	var comment model.Comment
+	err := row.Scan(
+		&comment.ID,
+		&comment.PostID,
+		&comment.ParentID,
+		&comment.UserID,
+		&comment.Text,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+		&comment.VoteCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &comment, err
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/entities"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"github.com/lib/pq"
+	"time"
+)
+
+type communityRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewCommunityRepository(db *sql.DB) domain.CommunityRepository {
+	return &communityRepositoryImpl{
+		db: db,
+	}
+}
+
+func (c *communityRepositoryImpl) GetAll(filter *helpers.PaginateFilter) (*[]model.Community, helpers.Metadata, error) {
+	query := fmt.Sprintf(
+		`
+			SELECT
+				COUNT(*) OVER() AS total_records,
+				c.id,
+				c.name,
+				COALESCE(c.description, ''),
+				c.creator_id,
+				c.created_at,
+				ARRAY(
+					SELECT m.user_id::text FROM community_moderators m WHERE m.community_id = c.id ORDER BY m.created_at
+				) AS moderators
+			FROM
+				communities c
+			ORDER BY
+				%s %s
+			LIMIT
+				$1
+			OFFSET
+				$2;
+        `,
+		filter.SortValue(),
+		filter.SortDirection(),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	rows, err := c.db.QueryContext(ctx, query, filter.Limit(), filter.OffSet())
+	if err != nil {
+		return nil, helpers.Metadata{}, err
+	}
+	defer rows.Close()
+	var communities []model.Community
+	var totalRecords int
+	for rows.Next() {
+		var community model.Community
+		err := rows.Scan(
+			&totalRecords,
+			&community.ID,
+			&community.Name,
+			&community.Description,
+			&community.CreatorID,
+			&community.CreatedAt,
+			pq.Array(&community.Moderators),
+		)
+		if err != nil {
+			return nil, helpers.Metadata{}, err
+		}
+		communities = append(communities, community)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, helpers.Metadata{}, err
+	}
+	metadata := helpers.CalculateMetadata(totalRecords, filter.Page, filter.PageSize)
+	return &communities, metadata, nil
+}
+
+func (c *communityRepositoryImpl) GetByID(communityID string) (*model.Community, error) {
+	query := `
+			SELECT
+				c.id,
+				c.name,
+				COALESCE(c.description, ''),
+				c.creator_id,
+				c.created_at,
+				ARRAY(
+					SELECT m.user_id::text FROM community_moderators m WHERE m.community_id = c.id ORDER BY m.created_at
+				) AS moderators
+			FROM communities c
+			WHERE c.id = $1
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	row := c.db.QueryRowContext(ctx, query, communityID)
+	return collectCommunityRow(row)
+}
+
+func (c *communityRepositoryImpl) GetByName(name string) (*model.Community, error) {
+	query := `
+			SELECT
+				c.id,
+				c.name,
+				COALESCE(c.description, ''),
+				c.creator_id,
+				c.created_at,
+				ARRAY(
+					SELECT m.user_id::text FROM community_moderators m WHERE m.community_id = c.id ORDER BY m.created_at
+				) AS moderators
+			FROM communities c
+			WHERE LOWER(c.name) = LOWER($1)
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	row := c.db.QueryRowContext(ctx, query, name)
+	return collectCommunityRow(row)
+}
+
+func (c *communityRepositoryImpl) Create(community *entities.CommunityCreateRequest, userID string) (*model.Community, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var communityID string
+	query := "INSERT INTO communities (name, description, creator_id) VALUES ($1, $2, $3) RETURNING id"
+	args := []any{community.Name, community.Description, userID}
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&communityID); err != nil {
+		return nil, err
+	}
+	query = "INSERT INTO community_moderators (community_id, user_id) VALUES ($1, $2)"
+	if _, err := tx.ExecContext(ctx, query, communityID, userID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return c.GetByID(communityID)
+}
+
+func (c *communityRepositoryImpl) Update(community *entities.CommunityUpdateRequest, communityID string) (*model.Community, error) {
+	query := "UPDATE communities SET description = $1 WHERE id = $2"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	if _, err := c.db.ExecContext(ctx, query, community.Description, communityID); err != nil {
+		return nil, err
+	}
+	return c.GetByID(communityID)
+}
+
+func (c *communityRepositoryImpl) IsModerator(communityID, userID string) (bool, error) {
+	query := "SELECT EXISTS (SELECT 1 FROM community_moderators WHERE community_id = $1 AND user_id = $2)"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	var exists bool
+	err := c.db.QueryRowContext(ctx, query, communityID, userID).Scan(&exists)
+	return exists, err
+}
+
+func (c *communityRepositoryImpl) AddModerator(communityID, userID string) error {
+	query := "INSERT INTO community_moderators (community_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	_, err := c.db.ExecContext(ctx, query, communityID, userID)
+	return err
+}
+
+func (c *communityRepositoryImpl) RemoveModerator(communityID, userID string) error {
+	query := "DELETE FROM community_moderators WHERE community_id = $1 AND user_id = $2"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	_, err := c.db.ExecContext(ctx, query, communityID, userID)
+	return err
+}
+
+func collectCommunityRow(row *sql.Row) (*model.Community, error) {
+	var community model.Community
+	err := row.Scan(
+		&community.ID,
+		&community.Name,
+		&community.Description,
+		&community.CreatorID,
+		&community.CreatedAt,
+		pq.Array(&community.Moderators),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &community, nil
+}
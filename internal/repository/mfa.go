@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"time"
+)
+
+type mfaRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewMFARepository(db *sql.DB) domain.MFARepository {
+	return &mfaRepositoryImpl{
+		db: db,
+	}
+}
+
+// SetPendingTOTPSecret stores the secret of a new enrollment, replacing one
+// that was never confirmed. it returns sql.ErrNoRows when TOTP is already enabled.
+func (m *mfaRepositoryImpl) SetPendingTOTPSecret(userID, secret string) error {
+	query := "UPDATE users SET totp_secret = $2 WHERE id = $1 AND totp_enabled_at IS NULL RETURNING id"
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	var id string
+	return m.db.QueryRowContext(ctx, query, userID, secret).Scan(&id)
+}
+
+// EnableTOTP confirms the pending enrollment of a user together with a fresh
+// set of recovery codes. it returns sql.ErrNoRows when there is nothing to confirm.
+func (m *mfaRepositoryImpl) EnableTOTP(userID string, codeHashes []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	query := `
+                UPDATE users SET totp_enabled_at = CURRENT_TIMESTAMP
+                WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL
+                RETURNING id
+        `
+	var id string
+	if err := tx.QueryRowContext(ctx, query, userID).Scan(&id); err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *mfaRepositoryImpl) DisableTOTP(userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	query := "UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL WHERE id = $1"
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return err
+	}
+	query = "DELETE FROM recovery_codes WHERE user_id = $1"
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *mfaRepositoryImpl) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseRecoveryCode uses up a recovery code of a user. it returns sql.ErrNoRows
+// when the code is unknown or was already used.
+func (m *mfaRepositoryImpl) UseRecoveryCode(userID, codeHash string) error {
+	query := `
+                UPDATE recovery_codes SET used_at = CURRENT_TIMESTAMP
+                WHERE id = (
+                    SELECT id FROM recovery_codes
+                    WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+                    LIMIT 1
+                    FOR UPDATE
+                )
+                RETURNING id
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	var id string
+	return m.db.QueryRowContext(ctx, query, userID, codeHash).Scan(&id)
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string, codeHashes []string) error {
+	query := "DELETE FROM recovery_codes WHERE user_id = $1"
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return err
+	}
+	query = "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)"
+	for _, codeHash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, query, userID, codeHash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,11 +3,15 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"github.com/arshamroshannejad/task-rootext/internal/domain"
 	"github.com/arshamroshannejad/task-rootext/internal/entities"
 	"github.com/arshamroshannejad/task-rootext/internal/helpers"
 	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"github.com/lib/pq"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -21,61 +25,170 @@ func NewPostRepository(db *sql.DB) domain.PostRepository {
 	}
 }
 
-func (p *postRepositoryImpl) GetAll(filter *helpers.PaginateFilter) (*[]model.Post, helpers.Metadata, error) {
+func (p *postRepositoryImpl) GetAll(filter *helpers.PaginateFilter, postFilter *entities.PostFilter) (*[]model.Post, helpers.Metadata, error) {
+	if filter.UseCursor {
+		return p.getAllByCursor(filter, postFilter)
+	}
+	conditions, args := postConditions(postFilter)
 	query := fmt.Sprintf(
 		`
 			SELECT 
 				COUNT(*) OVER() AS total_records,
-				p.id, 
-				p.title, 
-				p.text, 
-				p.created_at, 
-				p.updated_at, 
-				p.user_id, 
-				COALESCE(SUM(v.vote), 0) AS vote_count
+				`+postSelectColumns+`
 			FROM 
 				posts p
-			LEFT JOIN 
-				votes v ON p.id = v.post_id
-			GROUP BY 
-				p.id
+			WHERE
+				%s
 			ORDER BY 
 				%s %s
 			LIMIT
-				$1 
+				$%d 
 			OFFSET 
-				$2;
+				$%d;
         `,
-		filter.SortValue(),
+		strings.Join(conditions, " AND "),
+		postSortColumns[filter.SortValue()],
 		filter.SortDirection(),
+		len(args)+1,
+		len(args)+2,
 	)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 	defer cancel()
-	rows, err := p.db.QueryContext(ctx, query, filter.Limit(), filter.OffSet())
+	args = append(args, filter.Limit(), filter.OffSet())
+	rows, err := p.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, helpers.Metadata{}, err
 	}
 	defer rows.Close()
-	return collectPostRows(rows, filter.Limit(), filter.OffSet())
+	return collectPostRows(rows, filter.Page, filter.PageSize)
+}
+
+// getAllByCursor seeks on (sort key, id) instead of using OFFSET, so deep pages
+// stay cheap and rows do not shift between pages when new votes arrive.
+func (p *postRepositoryImpl) getAllByCursor(filter *helpers.PaginateFilter, postFilter *entities.PostFilter) (*[]model.Post, helpers.Metadata, error) {
+	conditions, args := postConditions(postFilter)
+	sortColumn := postSortColumns[filter.SortValue()]
+	direction := filter.SortDirection()
+	backward := filter.Cursor != nil && filter.Cursor.Backward
+	if backward {
+		direction = reverseDirection(direction)
+	}
+	seek := "TRUE"
+	if filter.Cursor != nil {
+		operator := ">"
+		if direction == "DESC" {
+			operator = "<"
+		}
+		args = append(args, filter.Cursor.Value, filter.Cursor.ID)
+		seek = fmt.Sprintf("(t.sort_key, t.id) %s ($%d::%s, $%d::int)", operator, len(args)-1, entities.PostCursorTypes[filter.SortValue()], len(args))
+	}
+	query := fmt.Sprintf(
+		`
+			SELECT
+				t.id,
+				t.title,
+				t.text,
+				t.created_at,
+				t.updated_at,
+				t.edited_at,
+				t.user_id,
+				t.community_id,
+				t.status,
+				t.publish_at,
+				t.tags,
+				t.attachments,
+				t.upvotes,
+				t.downvotes,
+				t.vote_count,
+				t.sort_key::text
+			FROM (
+				SELECT
+					`+postSelectColumns+`,
+					%s AS sort_key
+				FROM
+					posts p
+				WHERE
+					%s
+			) t
+			WHERE
+				%s
+			ORDER BY
+				t.sort_key %s, t.id %s
+			LIMIT
+				$%d;
+        `,
+		sortColumn,
+		strings.Join(conditions, " AND "),
+		seek,
+		direction,
+		direction,
+		len(args)+1,
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	args = append(args, filter.Limit()+1)
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, helpers.Metadata{}, err
+	}
+	defer rows.Close()
+	var posts []model.Post
+	var sortKeys []string
+	for rows.Next() {
+		var post model.Post
+		var sortKey string
+		err := rows.Scan(
+			&post.ID,
+			&post.Title,
+			&post.Text,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&post.EditedAt,
+			&post.UserID,
+			&post.CommunityID,
+			&post.Status,
+			&post.PublishAt,
+			pq.Array(&post.Tags),
+			jsonColumn(&post.Attachments),
+			&post.Upvotes,
+			&post.Downvotes,
+			&post.VoteCount,
+			&sortKey,
+		)
+		if err != nil {
+			return nil, helpers.Metadata{}, err
+		}
+		post.Edited = post.EditedAt != nil
+		posts = append(posts, post)
+		sortKeys = append(sortKeys, sortKey)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, helpers.Metadata{}, err
+	}
+	hasMore := len(posts) > filter.Limit()
+	if hasMore {
+		posts = posts[:filter.Limit()]
+		sortKeys = sortKeys[:filter.Limit()]
+	}
+	if backward {
+		slices.Reverse(posts)
+		slices.Reverse(sortKeys)
+	}
+	if len(posts) == 0 {
+		return &posts, helpers.Metadata{PageSize: filter.PageSize}, nil
+	}
+	first := helpers.Cursor{Sort: filter.Sort, Value: sortKeys[0], ID: posts[0].ID}
+	last := helpers.Cursor{Sort: filter.Sort, Value: sortKeys[len(sortKeys)-1], ID: posts[len(posts)-1].ID}
+	return &posts, helpers.CalculateCursorMetadata(filter, first, last, hasMore), nil
 }
 
 func (p *postRepositoryImpl) GetByID(postID string) (*model.Post, error) {
 	query := `
 			SELECT 
-				p.id,
-				p.title,
-				p.text,
-				p.created_at,
-				p.updated_at,
-				p.user_id,
-				COALESCE(SUM(v.vote), 0) as vote_count
+				` + postSelectColumns + `
 			FROM posts p
-			LEFT JOIN 
-			    votes v ON p.id = v.post_id
 			WHERE 
-			    p.id = $1
-			GROUP BY 
-			    p.id
+			    p.id = $1 AND p.deleted_at IS NULL
         `
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 	defer cancel()
@@ -83,14 +196,26 @@ func (p *postRepositoryImpl) GetByID(postID string) (*model.Post, error) {
 	return collectPostRow(row)
 }
 
+func (p *postRepositoryImpl) GetDeletedByID(postID string, window time.Duration) (*model.Post, error) {
+	query := `
+			SELECT 
+				` + postSelectColumns + `
+			FROM posts p
+			WHERE 
+			    p.id = $1 AND p.deleted_at > NOW() - $2::interval
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	row := p.db.QueryRowContext(ctx, query, postID, intervalOf(window))
+	return collectPostRow(row)
+}
+
 func (p *postRepositoryImpl) GetByTitle(title string) (*model.Post, error) {
 	query := `
                 SELECT 
-                    p.id, p.title, p.text, p.created_at, p.updated_at, p.user_id, COALESCE(SUM(v.vote), 0) as vote_count
+                    ` + postSelectColumns + `
                 FROM posts p
-                LEFT JOIN votes v ON p.id = v.post_id
-                WHERE p.title = $1
-                GROUP BY p.id
+                WHERE p.title = $1 AND p.deleted_at IS NULL
         `
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 	defer cancel()
@@ -98,66 +223,723 @@ func (p *postRepositoryImpl) GetByTitle(title string) (*model.Post, error) {
 	return collectPostRow(row)
 }
 
-func (p *postRepositoryImpl) Create(post *entities.PostCreateUpdateRequest, userID string) (*model.Post, error) {
+func (p *postRepositoryImpl) Search(query string, filter *helpers.PaginateFilter, postFilter *entities.PostFilter) (*[]model.PostSearchResult, helpers.Metadata, error) {
+	conditions, args := postConditions(postFilter)
+	args = append(args, query)
+	conditions = append(conditions, fmt.Sprintf("p.search_vector @@ websearch_to_tsquery('english', $%d)", len(args)))
+	// headlines are expensive, so they are only built for the rows of the requested page
+	searchQuery := fmt.Sprintf(
+		`
+			WITH matches AS (
+				SELECT
+					COUNT(*) OVER() AS total_records,
+					`+postSelectColumns+`,
+					ts_rank_cd(p.search_vector, websearch_to_tsquery('english', $%[1]d)) AS rank
+				FROM
+					posts p
+				WHERE
+					%[2]s
+				ORDER BY
+					%[3]s %[4]s, p.id DESC
+				LIMIT
+					$%[5]d
+				OFFSET
+					$%[6]d
+			)
+			SELECT
+				m.total_records,
+				m.id,
+				m.title,
+				m.text,
+				m.created_at,
+				m.updated_at,
+				m.edited_at,
+				m.user_id,
+				m.community_id,
+				m.status,
+				m.publish_at,
+				m.tags,
+				m.attachments,
+				m.upvotes,
+				m.downvotes,
+				m.vote_count,
+				m.rank,
+				ts_headline('english', %[7]s, websearch_to_tsquery('english', $%[1]d),
+					'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS title_highlight,
+				ts_headline('english', %[8]s, websearch_to_tsquery('english', $%[1]d),
+					'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10') AS snippet
+			FROM
+				matches m
+			ORDER BY
+				m.%[3]s %[4]s, m.id DESC;
+        `,
+		len(args),
+		strings.Join(conditions, " AND "),
+		filter.SortValue(),
+		filter.SortDirection(),
+		len(args)+1,
+		len(args)+2,
+		escapeHTML("m.title"),
+		escapeHTML("COALESCE(m.text, '')"),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	args = append(args, filter.Limit(), filter.OffSet())
+	rows, err := p.db.QueryContext(ctx, searchQuery, args...)
+	if err != nil {
+		return nil, helpers.Metadata{}, err
+	}
+	defer rows.Close()
+	var results []model.PostSearchResult
+	var totalRecords int
+	for rows.Next() {
+		var result model.PostSearchResult
+		err := rows.Scan(
+			&totalRecords,
+			&result.ID,
+			&result.Title,
+			&result.Text,
+			&result.CreatedAt,
+			&result.UpdatedAt,
+			&result.EditedAt,
+			&result.UserID,
+			&result.CommunityID,
+			&result.Status,
+			&result.PublishAt,
+			pq.Array(&result.Tags),
+			jsonColumn(&result.Attachments),
+			&result.Upvotes,
+			&result.Downvotes,
+			&result.VoteCount,
+			&result.Rank,
+			&result.TitleHighlight,
+			&result.Snippet,
+		)
+		if err != nil {
+			return nil, helpers.Metadata{}, err
+		}
+		result.Edited = result.EditedAt != nil
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, helpers.Metadata{}, err
+	}
+	metadata := helpers.CalculateMetadata(totalRecords, filter.Page, filter.PageSize)
+	return &results, metadata, nil
+}
+
+func (p *postRepositoryImpl) GetDraftsByUser(userID string, filter *helpers.PaginateFilter) (*[]model.Post, helpers.Metadata, error) {
+	query := fmt.Sprintf(
+		`
+			SELECT 
+				COUNT(*) OVER() AS total_records,
+				`+postSelectColumns+`
+			FROM 
+				posts p
+			WHERE
+				p.user_id = $1 AND p.status <> 'published' AND p.deleted_at IS NULL
+			ORDER BY 
+				p.%s %s NULLS LAST, p.id DESC
+			LIMIT
+				$2 
+			OFFSET 
+				$3;
+        `,
+		filter.SortValue(),
+		filter.SortDirection(),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	rows, err := p.db.QueryContext(ctx, query, userID, filter.Limit(), filter.OffSet())
+	if err != nil {
+		return nil, helpers.Metadata{}, err
+	}
+	defer rows.Close()
+	return collectPostRows(rows, filter.Page, filter.PageSize)
+}
+
+func (p *postRepositoryImpl) GetSavedByUser(userID string, filter *helpers.PaginateFilter) (*[]model.Post, helpers.Metadata, error) {
+	query := fmt.Sprintf(
+		`
+			SELECT 
+				COUNT(*) OVER() AS total_records,
+				`+postSelectColumns+`
+			FROM 
+				saved_posts s
+			JOIN
+				posts p ON p.id = s.post_id
+			WHERE
+				s.user_id = $1 AND p.status = 'published' AND p.deleted_at IS NULL
+			ORDER BY 
+				%s %s, p.id DESC
+			LIMIT
+				$2 
+			OFFSET 
+				$3;
+        `,
+		savedPostSortColumns[filter.SortValue()],
+		filter.SortDirection(),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	rows, err := p.db.QueryContext(ctx, query, userID, filter.Limit(), filter.OffSet())
+	if err != nil {
+		return nil, helpers.Metadata{}, err
+	}
+	defer rows.Close()
+	return collectPostRows(rows, filter.Page, filter.PageSize)
+}
+
+// GetViewerStates returns what the user has done with each of the given posts
+// in a single query, however many posts are asked for.
+func (p *postRepositoryImpl) GetViewerStates(userID string, postIDs []string) (map[string]model.PostViewerState, error) {
 	query := `
-                INSERT INTO posts (title, text, user_id) 
-                VALUES ($1, $2, $3) 
-                RETURNING id, title, text, created_at, updated_at, user_id, 0 as vote_count
+                SELECT ids.id, s.post_id IS NOT NULL AS saved, COALESCE(v.vote, 0) AS my_vote
+                FROM UNNEST($2::int[]) AS ids(id)
+                LEFT JOIN saved_posts s ON s.post_id = ids.id AND s.user_id = $1
+                LEFT JOIN votes v ON v.post_id = ids.id AND v.user_id = $1
         `
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 	defer cancel()
-	args := []any{post.Title, post.Text, userID}
-	row := p.db.QueryRowContext(ctx, query, args...)
-	if row.Err() != nil {
-		return nil, row.Err()
+	rows, err := p.db.QueryContext(ctx, query, userID, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
 	}
-	return collectPostRow(row)
+	defer rows.Close()
+	states := make(map[string]model.PostViewerState, len(postIDs))
+	for rows.Next() {
+		var postID string
+		var state model.PostViewerState
+		if err := rows.Scan(&postID, &state.Saved, &state.MyVote); err != nil {
+			return nil, err
+		}
+		states[postID] = state
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+func (p *postRepositoryImpl) Create(post *entities.PostCreateRequest, userID string) (*model.Post, error) {
+	query := `
+                INSERT INTO posts AS p (title, text, user_id, community_id, status, publish_at, hot_score) 
+                VALUES (
+                    $1, $2, $3, $4, $5,
+                    CASE $5 WHEN 'published' THEN CURRENT_TIMESTAMP WHEN 'scheduled' THEN $6::timestamptz END,
+                    hot_score(0, 0, CURRENT_TIMESTAMP)
+                ) 
+                RETURNING ` + postSelectColumns + `
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	status := post.Status
+	if status == "" {
+		status = model.PostStatusPublished
+	}
+	args := []any{post.Title, post.Text, userID, post.CommunityID, status, post.PublishAt}
+	createdPost, err := collectPostRow(tx.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return nil, err
+	}
+	// drafts and scheduled posts get their first revision when they are published
+	if createdPost.Status == model.PostStatusPublished {
+		if err := insertPostRevision(ctx, tx, createdPost.ID, post.Title, post.Text); err != nil {
+			return nil, err
+		}
+	}
+	if len(post.Tags) > 0 {
+		if err := setPostTags(ctx, tx, createdPost.ID, post.Tags); err != nil {
+			return nil, err
+		}
+		createdPost.Tags = slices.Sorted(slices.Values(post.Tags))
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return createdPost, nil
 }
 
 func (p *postRepositoryImpl) Update(post *entities.PostCreateUpdateRequest, postID string) (*model.Post, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var currentTitle, currentText, status string
+	query := "SELECT title, COALESCE(text, ''), status FROM posts WHERE id = $1 FOR UPDATE"
+	if err := tx.QueryRowContext(ctx, query, postID).Scan(&currentTitle, &currentText, &status); err != nil {
+		return nil, err
+	}
+	edited := currentTitle != post.Title || currentText != post.Text
+	query = `
+                UPDATE posts p
+                SET title = $1, text = $2, community_id = $3, updated_at = CURRENT_TIMESTAMP,
+                    edited_at = CASE WHEN $5 AND status = 'published' THEN CURRENT_TIMESTAMP ELSE edited_at END
+                WHERE id = $4 
+                RETURNING ` + postSelectColumns + `
+        `
+	args := []any{post.Title, post.Text, post.CommunityID, postID, edited}
+	updatedPost, err := collectPostRow(tx.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return nil, err
+	}
+	// edits of unpublished posts are not part of the public history
+	if edited && status == model.PostStatusPublished {
+		if err := insertPostRevision(ctx, tx, postID, post.Title, post.Text); err != nil {
+			return nil, err
+		}
+	}
+	if post.Tags != nil {
+		if err := setPostTags(ctx, tx, postID, post.Tags); err != nil {
+			return nil, err
+		}
+		updatedPost.Tags = slices.Sorted(slices.Values(post.Tags))
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return updatedPost, nil
+}
+
+func (p *postRepositoryImpl) Publish(postID string, publishAt *time.Time) error {
+	// a publish_at in the future schedules the post, anything else publishes it right away
 	query := `
-                UPDATE posts 
-                SET title = $1, text = $2, updated_at = CURRENT_TIMESTAMP 
-                WHERE id = $3 
-                RETURNING id, title, text, created_at, updated_at, user_id, 0 as vote_count
+                UPDATE posts
+                SET status = CASE WHEN $2::timestamptz > NOW() THEN 'scheduled' ELSE 'published' END,
+                    publish_at = CASE WHEN $2::timestamptz > NOW() THEN $2::timestamptz ELSE CURRENT_TIMESTAMP END,
+                    hot_score = hot_score(upvotes, downvotes, CURRENT_TIMESTAMP)
+                WHERE id = $1 AND status <> 'published' AND deleted_at IS NULL
+                RETURNING status, title, COALESCE(text, '')
         `
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 	defer cancel()
-	args := []any{post.Title, post.Text, postID}
-	row := p.db.QueryRowContext(ctx, query, args...)
-	if row.Err() != nil {
-		return nil, row.Err()
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
-	return collectPostRow(row)
+	defer tx.Rollback()
+	var status, title, text string
+	if err := tx.QueryRowContext(ctx, query, postID, publishAt).Scan(&status, &title, &text); err != nil {
+		return err
+	}
+	// the content the post is published with is the first revision of its history
+	if status == model.PostStatusPublished {
+		if err := insertPostRevision(ctx, tx, postID, title, text); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (p *postRepositoryImpl) PublishScheduled() (int64, error) {
+	// like Publish, the content a post is published with becomes its first revision
+	query := `
+                WITH published AS (
+                    UPDATE posts
+                    SET status = 'published',
+                        hot_score = hot_score(upvotes, downvotes, publish_at)
+                    WHERE status = 'scheduled' AND publish_at <= NOW() AND deleted_at IS NULL
+                    RETURNING id, title, text
+                ), revisions AS (
+                    INSERT INTO post_revisions (post_id, revision, title, text)
+                    SELECT id, COALESCE((SELECT MAX(revision) FROM post_revisions r WHERE r.post_id = published.id), 0) + 1, title, text
+                    FROM published
+                )
+                SELECT COUNT(*) FROM published
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	var published int64
+	err := p.db.QueryRowContext(ctx, query).Scan(&published)
+	return published, err
+}
+
+func (p *postRepositoryImpl) GetRevisions(postID string) (*[]model.PostRevision, error) {
+	query := `
+                SELECT post_id, revision, title, COALESCE(text, ''), created_at
+                FROM post_revisions
+                WHERE post_id = $1
+                ORDER BY revision DESC
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	rows, err := p.db.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var revisions []model.PostRevision
+	for rows.Next() {
+		var revision model.PostRevision
+		err := rows.Scan(&revision.PostID, &revision.Revision, &revision.Title, &revision.Text, &revision.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &revisions, nil
+}
+
+func (p *postRepositoryImpl) GetRevision(postID string, revision int) (*model.PostRevision, error) {
+	query := `
+                SELECT post_id, revision, title, COALESCE(text, ''), created_at
+                FROM post_revisions
+                WHERE post_id = $1 AND revision = $2
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	var postRevision model.PostRevision
+	err := p.db.QueryRowContext(ctx, query, postID, revision).Scan(
+		&postRevision.PostID,
+		&postRevision.Revision,
+		&postRevision.Title,
+		&postRevision.Text,
+		&postRevision.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &postRevision, nil
 }
 
 func (p *postRepositoryImpl) Delete(postID string) error {
-	query := "DELETE FROM posts WHERE id = $1"
+	query := "UPDATE posts SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL"
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 	defer cancel()
 	_, err := p.db.ExecContext(ctx, query, postID)
 	return err
 }
 
+func (p *postRepositoryImpl) Restore(postID string, window time.Duration) error {
+	query := "UPDATE posts SET deleted_at = NULL WHERE id = $1 AND deleted_at > NOW() - $2::interval"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	result, err := p.db.ExecContext(ctx, query, postID, intervalOf(window))
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (p *postRepositoryImpl) Purge(window time.Duration) (int64, error) {
+	// votes, comments and revisions of the post go with it through ON DELETE CASCADE
+	query := "DELETE FROM posts WHERE deleted_at <= NOW() - $1::interval"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	result, err := p.db.ExecContext(ctx, query, intervalOf(window))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 func (p *postRepositoryImpl) AddVote(postID, userID, vote string) error {
-	query := "INSERT INTO votes (user_id, post_id, vote) VALUES ($1, $2, $3) ON CONFLICT (user_id, post_id) DO UPDATE SET vote = $4"
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 	defer cancel()
-	args := []any{postID, userID, vote, vote}
-	_, err := p.db.ExecContext(ctx, query, args...)
-	return err
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	previous, err := lockPostVote(ctx, tx, postID, userID)
+	if err != nil {
+		return err
+	}
+	query := `
+                INSERT INTO votes (user_id, post_id, vote) VALUES ($1, $2, $3)
+                ON CONFLICT (user_id, post_id) DO UPDATE SET vote = $3, created_at = CURRENT_TIMESTAMP
+                RETURNING vote
+        `
+	var current int
+	if err := tx.QueryRowContext(ctx, query, userID, postID, vote).Scan(&current); err != nil {
+		return err
+	}
+	if err := applyPostVote(ctx, tx, postID, previous, current); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (p *postRepositoryImpl) RemoveVote(postID, userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	previous, err := lockPostVote(ctx, tx, postID, userID)
+	if err != nil {
+		return err
+	}
+	if previous == 0 {
+		return nil
+	}
 	query := "DELETE FROM votes WHERE user_id = $1 AND post_id = $2"
+	if _, err := tx.ExecContext(ctx, query, userID, postID); err != nil {
+		return err
+	}
+	if err := applyPostVote(ctx, tx, postID, previous, 0); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReconcileVoteCounts recounts the votes of every post and fixes the counters
+// that drifted from the votes table. it returns the number of posts fixed.
+func (p *postRepositoryImpl) ReconcileVoteCounts() (int64, error) {
+	query := `
+                UPDATE posts p
+                SET upvotes = c.upvotes,
+                    downvotes = c.downvotes,
+                    score = c.upvotes - c.downvotes,
+                    hot_score = hot_score(c.upvotes, c.downvotes, COALESCE(p.publish_at, p.created_at)),
+                    best_score = best_score(c.upvotes, c.downvotes),
+                    controversial_score = controversial_score(c.upvotes, c.downvotes)
+                FROM (
+                    SELECT
+                        po.id,
+                        COUNT(*) FILTER (WHERE v.vote = 1)::INTEGER AS upvotes,
+                        COUNT(*) FILTER (WHERE v.vote = -1)::INTEGER AS downvotes
+                    FROM posts po
+                    LEFT JOIN votes v ON v.post_id = po.id
+                    GROUP BY po.id
+                ) c
+                WHERE p.id = c.id
+                    AND (p.upvotes, p.downvotes, p.score) IS DISTINCT FROM (c.upvotes, c.downvotes, c.upvotes - c.downvotes)
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+	result, err := p.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (p *postRepositoryImpl) Save(postID, userID string) error {
+	query := "INSERT INTO saved_posts (user_id, post_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 	defer cancel()
-	args := []any{postID, userID}
-	_, err := p.db.ExecContext(ctx, query, args...)
+	_, err := p.db.ExecContext(ctx, query, userID, postID)
+	return err
+}
+
+func (p *postRepositoryImpl) Unsave(postID, userID string) error {
+	query := "DELETE FROM saved_posts WHERE user_id = $1 AND post_id = $2"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	_, err := p.db.ExecContext(ctx, query, userID, postID)
+	return err
+}
+
+func (p *postRepositoryImpl) UpdateRisingScores(window time.Duration) error {
+	// net votes of the last hour, damped by the time since the post was published. posts older
+	// than the window drop out of the rising listing.
+	query := `
+                UPDATE posts p
+                SET rising_score = CASE
+                    WHEN p.status = 'published' AND p.publish_at > NOW() - $1::interval THEN
+                        (SELECT COALESCE(SUM(v.vote), 0) FROM votes v WHERE v.post_id = p.id AND v.created_at > NOW() - INTERVAL '1 hour')
+                        / POWER(EXTRACT(EPOCH FROM NOW() - p.publish_at)::DOUBLE PRECISION / 3600 + 2, 1.5)
+                    ELSE 0
+                END
+                WHERE p.publish_at > NOW() - $1::interval OR p.rising_score <> 0
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	_, err := p.db.ExecContext(ctx, query, intervalOf(window))
+	return err
+}
+
+// escapeHTML wraps a text expression in the SQL that html escapes it. headlines
+// are built from the escaped text, so the <mark> tags around the matches are the
+// only html in them.
+func escapeHTML(expression string) string {
+	for _, r := range [][2]string{{"&", "&amp;"}, {"<", "&lt;"}, {">", "&gt;"}, {`"`, "&quot;"}, {"'", "&#39;"}} {
+		expression = fmt.Sprintf("REPLACE(%s, '%s', '%s')", expression, strings.ReplaceAll(r[0], "'", "''"), r[1])
+	}
+	return expression
+}
+
+// insertPostRevision appends the given content as the next revision of a post.
+// callers hold the row lock of the post, so revision numbers can not collide.
+func insertPostRevision(ctx context.Context, tx *sql.Tx, postID, title, text string) error {
+	query := `
+                INSERT INTO post_revisions (post_id, revision, title, text)
+                SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3 FROM post_revisions WHERE post_id = $1
+        `
+	_, err := tx.ExecContext(ctx, query, postID, title, text)
+	return err
+}
+
+// setPostTags replaces the tags of a post, creating the tags that do not exist yet.
+func setPostTags(ctx context.Context, tx *sql.Tx, postID string, tags []string) error {
+	query := "DELETE FROM post_tags WHERE post_id = $1"
+	if _, err := tx.ExecContext(ctx, query, postID); err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	query = "INSERT INTO tags (name) SELECT UNNEST($1::text[]) ON CONFLICT (name) DO NOTHING"
+	if _, err := tx.ExecContext(ctx, query, pq.Array(tags)); err != nil {
+		return err
+	}
+	query = "INSERT INTO post_tags (post_id, tag_id) SELECT $1, id FROM tags WHERE name = ANY($2::text[])"
+	_, err := tx.ExecContext(ctx, query, postID, pq.Array(tags))
+	return err
+}
+
+// lockPostVote locks the post row, so votes on the same post are counted one
+// after another, and returns the current vote of the user, 0 when there is none.
+func lockPostVote(ctx context.Context, tx *sql.Tx, postID, userID string) (int, error) {
+	query := `
+                SELECT COALESCE((SELECT vote FROM votes WHERE user_id = $2 AND post_id = $1), 0)
+                FROM posts
+                WHERE id = $1
+                FOR UPDATE
+        `
+	var vote int
+	err := tx.QueryRowContext(ctx, query, postID, userID).Scan(&vote)
+	return vote, err
+}
+
+// applyPostVote moves the vote counters of a post from the previous vote of a
+// user to the current one and recomputes the rankings that only depend on them,
+// so listings can order by precomputed columns.
+func applyPostVote(ctx context.Context, tx *sql.Tx, postID string, previous, current int) error {
+	upvotes := voteDelta(previous, current, 1)
+	downvotes := voteDelta(previous, current, -1)
+	if upvotes == 0 && downvotes == 0 {
+		return nil
+	}
+	query := `
+                UPDATE posts p
+                SET upvotes = c.upvotes,
+                    downvotes = c.downvotes,
+                    score = c.upvotes - c.downvotes,
+                    hot_score = hot_score(c.upvotes, c.downvotes, COALESCE(p.publish_at, p.created_at)),
+                    best_score = best_score(c.upvotes, c.downvotes),
+                    controversial_score = controversial_score(c.upvotes, c.downvotes)
+                FROM (
+                    SELECT upvotes + $2 AS upvotes, downvotes + $3 AS downvotes FROM posts WHERE id = $1
+                ) c
+                WHERE p.id = $1
+        `
+	_, err := tx.ExecContext(ctx, query, postID, upvotes, downvotes)
 	return err
 }
 
-func collectPostRows(rows *sql.Rows, limit, offset int) (*[]model.Post, helpers.Metadata, error) {
+// voteDelta is how much the counter of the given vote value changes when a
+// vote goes from previous to current.
+func voteDelta(previous, current, value int) int {
+	delta := 0
+	if current == value {
+		delta++
+	}
+	if previous == value {
+		delta--
+	}
+	return delta
+}
+
+// postSortColumns maps every sort value of the post listing to the SQL
+// expression it orders by. the type its cursor value is cast back to is in
+// entities.PostCursorTypes.
+var postSortColumns = map[string]string{
+	"created_at":    "p.created_at",
+	"vote_count":    "p.score",
+	"hot":           "p.hot_score",
+	"best":          "p.best_score",
+	"rising":        "p.rising_score",
+	"controversial": "p.controversial_score",
+}
+
+// savedPostSortColumns maps the sort values of the saved posts listing to the
+// column they order by.
+var savedPostSortColumns = map[string]string{
+	"saved_at":   "s.created_at",
+	"created_at": "p.created_at",
+}
+
+// intervalOf formats a duration as a postgres interval literal.
+func intervalOf(d time.Duration) string {
+	return fmt.Sprintf("%d seconds", int64(d.Seconds()))
+}
+
+func reverseDirection(direction string) string {
+	if direction == "DESC" {
+		return "ASC"
+	}
+	return "DESC"
+}
+
+// postConditions translates a PostFilter into WHERE conditions and their
+// positional arguments. drafts, scheduled and soft deleted posts are always
+// filtered out.
+func postConditions(postFilter *entities.PostFilter) ([]string, []any) {
+	conditions := []string{"p.status = 'published'", "p.deleted_at IS NULL"}
+	var args []any
+	if postFilter.CommunityID != "" {
+		args = append(args, postFilter.CommunityID)
+		conditions = append(conditions, fmt.Sprintf("p.community_id = $%d", len(args)))
+	}
+	if postFilter.UserID != "" {
+		args = append(args, postFilter.UserID)
+		conditions = append(conditions, fmt.Sprintf("p.user_id = $%d", len(args)))
+	}
+	if len(postFilter.Tags) > 0 {
+		args = append(args, pq.Array(postFilter.Tags))
+		matchingTags := fmt.Sprintf(
+			"FROM post_tags pt JOIN tags tg ON tg.id = pt.tag_id WHERE pt.post_id = p.id AND tg.name = ANY($%d::text[])",
+			len(args),
+		)
+		switch postFilter.TagMode {
+		case "all":
+			// tags are deduplicated, so matching every one of them means matching as many rows
+			args = append(args, len(postFilter.Tags))
+			conditions = append(conditions, fmt.Sprintf("(SELECT COUNT(*) %s) = $%d", matchingTags, len(args)))
+		default:
+			conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 %s)", matchingTags))
+		}
+	}
+	return conditions, args
+}
+
+// postSelectColumns are the columns of a post in the order collectPostRow and
+// collectPostRows scan them, for queries that name the posts table p.
+const postSelectColumns = `p.id,
+				p.title,
+				p.text,
+				p.created_at,
+				p.updated_at,
+				p.edited_at,
+				p.user_id,
+				p.community_id,
+				p.status,
+				p.publish_at,
+				ARRAY(SELECT tg.name FROM post_tags pt JOIN tags tg ON tg.id = pt.tag_id WHERE pt.post_id = p.id ORDER BY tg.name) AS tags,
+				COALESCE((SELECT json_agg(json_build_object('id', a.id::text, 'post_id', a.post_id::text, 'url', a.url, 'filename', a.filename, 'content_type', a.content_type, 'size', a.size, 'created_at', a.created_at) ORDER BY a.id) FROM attachments a WHERE a.post_id = p.id), '[]') AS attachments,
+				p.upvotes,
+				p.downvotes,
+				p.score AS vote_count`
+
+func collectPostRows(rows *sql.Rows, page, pageSize int) (*[]model.Post, helpers.Metadata, error) {
 	var posts []model.Post
 	var totalRecords int
 	for rows.Next() {
@@ -169,18 +951,27 @@ func collectPostRows(rows *sql.Rows, limit, offset int) (*[]model.Post, helpers.
 			&post.Text,
 			&post.CreatedAt,
 			&post.UpdatedAt,
+			&post.EditedAt,
 			&post.UserID,
+			&post.CommunityID,
+			&post.Status,
+			&post.PublishAt,
+			pq.Array(&post.Tags),
+			jsonColumn(&post.Attachments),
+			&post.Upvotes,
+			&post.Downvotes,
 			&post.VoteCount,
 		)
 		if err != nil {
 			return nil, helpers.Metadata{}, err
 		}
+		post.Edited = post.EditedAt != nil
 		posts = append(posts, post)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, helpers.Metadata{}, err
 	}
-	metadata := helpers.CalculateMetadata(totalRecords, offset, limit)
+	metadata := helpers.CalculateMetadata(totalRecords, page, pageSize)
 	return &posts, metadata, nil
 }
 
@@ -192,11 +983,40 @@ func collectPostRow(row *sql.Row) (*model.Post, error) {
 		&post.Text,
 		&post.CreatedAt,
 		&post.UpdatedAt,
+		&post.EditedAt,
 		&post.UserID,
+		&post.CommunityID,
+		&post.Status,
+		&post.PublishAt,
+		pq.Array(&post.Tags),
+		jsonColumn(&post.Attachments),
+		&post.Upvotes,
+		&post.Downvotes,
 		&post.VoteCount,
 	)
 	if err != nil {
 		return nil, err
 	}
+	post.Edited = post.EditedAt != nil
 	return &post, err
 }
+
+// jsonColumn scans a JSON column, such as an aggregated list of rows, into dst.
+func jsonColumn(dst any) sql.Scanner {
+	return jsonScanner{dst: dst}
+}
+
+type jsonScanner struct {
+	dst any
+}
+
+func (j jsonScanner) Scan(src any) error {
+	switch value := src.(type) {
+	case []byte:
+		return json.Unmarshal(value, j.dst)
+	case string:
+		return json.Unmarshal([]byte(value), j.dst)
+	default:
+		return fmt.Errorf("can not scan %T into a json column", src)
+	}
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the ranking functions live in the database (migrations/000007). these are
+// go versions of the same formulas: the table tests pin down how they rank
+// posts, TestRankingMigrationConstants ties them to the sql, and
+// TestRankingFunctionsMatchReference compares both on a postgres database when
+// TEST_POSTGRES_DSN names one.
+
+// hotEpoch is the creation time hot_score counts from, a post created then
+// with no votes scores zero.
+var hotEpoch = time.Unix(1134028003, 0)
+
+func hotScore(upvotes, downvotes int, createdAt time.Time) float64 {
+	net := float64(upvotes - downvotes)
+	sign := 0.0
+	switch {
+	case net > 0:
+		sign = 1
+	case net < 0:
+		sign = -1
+	}
+	return sign*math.Log10(math.Max(math.Abs(net), 1)) + float64(createdAt.Unix()-hotEpoch.Unix())/45000
+}
+
+func bestScore(upvotes, downvotes int) float64 {
+	n := float64(upvotes + downvotes)
+	if n == 0 {
+		return 0
+	}
+	up, down := float64(upvotes), float64(downvotes)
+	return ((up+1.9208)/n - 1.96*math.Sqrt(up*down/n+0.9604)/n) / (1 + 3.8416/n)
+}
+
+func controversialScore(upvotes, downvotes int) float64 {
+	if upvotes <= 0 || downvotes <= 0 {
+		return 0
+	}
+	balance := float64(upvotes) / float64(downvotes)
+	if upvotes > downvotes {
+		balance = float64(downvotes) / float64(upvotes)
+	}
+	return math.Pow(float64(upvotes+downvotes), balance)
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9
+}
+
+func TestHotScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		upvotes   int
+		downvotes int
+		createdAt time.Time
+		want      float64
+	}{
+		{name: "zero votes", createdAt: hotEpoch, want: 0},
+		{name: "tie", upvotes: 7, downvotes: 7, createdAt: hotEpoch, want: 0},
+		{name: "net one vote", upvotes: 1, createdAt: hotEpoch, want: 0},
+		{name: "ten net upvotes", upvotes: 12, downvotes: 2, createdAt: hotEpoch, want: 1},
+		{name: "hundred net upvotes", upvotes: 100, createdAt: hotEpoch, want: 2},
+		{name: "negative score", upvotes: 2, downvotes: 12, createdAt: hotEpoch, want: -1},
+		{name: "12.5 hours newer", createdAt: hotEpoch.Add(45000 * time.Second), want: 1},
+		{name: "ten votes make up for 12.5 hours", upvotes: 10, createdAt: hotEpoch.Add(-45000 * time.Second), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hotScore(tt.upvotes, tt.downvotes, tt.createdAt); !near(got, tt.want) {
+				t.Errorf("hotScore(%d, %d, %v) = %v, want %v", tt.upvotes, tt.downvotes, tt.createdAt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBestScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		upvotes   int
+		downvotes int
+		want      float64
+	}{
+		{name: "zero votes", want: 0},
+		{name: "single upvote", upvotes: 1, want: 0.20654329147389294},
+		{name: "ten upvotes", upvotes: 10, want: 0.7224598312333834},
+		{name: "tie", upvotes: 5, downvotes: 5, want: 0.2365895936154873},
+		{name: "mostly upvoted", upvotes: 10, downvotes: 5, want: 0.4171312515121297},
+		{name: "negative score", upvotes: 5, downvotes: 10, want: 0.15176100694691236},
+		{name: "only downvotes", downvotes: 10, want: 0},
+		{name: "many votes", upvotes: 100, downvotes: 1, want: 0.9460315253904807},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bestScore(tt.upvotes, tt.downvotes); !near(got, tt.want) {
+				t.Errorf("bestScore(%d, %d) = %v, want %v", tt.upvotes, tt.downvotes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestControversialScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		upvotes   int
+		downvotes int
+		want      float64
+	}{
+		{name: "zero votes", want: 0},
+		{name: "only upvotes", upvotes: 10, want: 0},
+		{name: "only downvotes", downvotes: 10, want: 0},
+		{name: "tie", upvotes: 5, downvotes: 5, want: 10},
+		{name: "mostly upvoted", upvotes: 10, downvotes: 5, want: 3.872983346207417},
+		{name: "negative score", upvotes: 5, downvotes: 10, want: 3.872983346207417},
+		{name: "lopsided", upvotes: 100, downvotes: 1, want: 1.0472327459898225},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := controversialScore(tt.upvotes, tt.downvotes); !near(got, tt.want) {
+				t.Errorf("controversialScore(%d, %d) = %v, want %v", tt.upvotes, tt.downvotes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankingOrder(t *testing.T) {
+	tests := []struct {
+		name          string
+		higher, lower float64
+	}{
+		{name: "hot: more net votes", higher: hotScore(20, 0, hotEpoch), lower: hotScore(10, 0, hotEpoch)},
+		{name: "hot: newer post", higher: hotScore(0, 0, hotEpoch.Add(time.Hour)), lower: hotScore(0, 0, hotEpoch)},
+		{name: "hot: tie above negative", higher: hotScore(5, 5, hotEpoch), lower: hotScore(0, 5, hotEpoch)},
+		{name: "best: same ratio, more votes", higher: bestScore(100, 10), lower: bestScore(10, 1)},
+		{name: "best: tie above mostly downvoted", higher: bestScore(5, 5), lower: bestScore(5, 10)},
+		{name: "best: one vote above none", higher: bestScore(1, 0), lower: bestScore(0, 0)},
+		{name: "controversial: more evenly split", higher: controversialScore(10, 9), lower: controversialScore(10, 2)},
+		{name: "controversial: bigger tie", higher: controversialScore(50, 50), lower: controversialScore(5, 5)},
+	}
+	for _, tt := range tests {
+		if tt.higher <= tt.lower {
+			t.Errorf("%s: %v is not above %v", tt.name, tt.higher, tt.lower)
+		}
+	}
+}
+
+// TestRankingMigrationConstants fails when the constants of the sql functions
+// change without the go versions above.
+func TestRankingMigrationConstants(t *testing.T) {
+	migration, err := os.ReadFile("../../migrations/000007_add_posts_ranking.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := string(migration)
+	functions := map[string][]string{
+		"hot_score":           {"SIGN(", "LOG(GREATEST(ABS(upvotes - downvotes), 1)", "1134028003", "/ 45000"},
+		"best_score":          {"1.9208", "1.96 * SQRT(", "0.9604", "3.8416"},
+		"controversial_score": {"WHEN upvotes <= 0 OR downvotes <= 0 THEN 0", "POWER((upvotes + downvotes)", "downvotes::DOUBLE PRECISION / upvotes", "upvotes::DOUBLE PRECISION / downvotes"},
+	}
+	for name, parts := range functions {
+		body := regexp.MustCompile(`(?s)CREATE FUNCTION ` + name + `\(.*?\$\$(.*?)\$\$`).FindStringSubmatch(source)
+		if body == nil {
+			t.Errorf("%s is not defined in the migration", name)
+			continue
+		}
+		for _, part := range parts {
+			if !strings.Contains(body[1], part) {
+				t.Errorf("%s no longer contains %q", name, part)
+			}
+		}
+	}
+}
+
+// TestRankingFunctionsMatchReference runs the sql functions on a migrated
+// postgres and compares them with the go versions. it only reads.
+func TestRankingFunctionsMatchReference(t *testing.T) {
+	db := openTestDB(t)
+	votes := []int{0, 1, 2, 5, 10, 99, 1000}
+	createdAt := []time.Time{hotEpoch, hotEpoch.Add(-time.Hour), time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)}
+	for _, up := range votes {
+		for _, down := range votes {
+			var best, controversial float64
+			err := db.QueryRow("SELECT best_score($1, $2), controversial_score($1, $2)", up, down).Scan(&best, &controversial)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !near(best, bestScore(up, down)) {
+				t.Errorf("best_score(%d, %d) = %v, want %v", up, down, best, bestScore(up, down))
+			}
+			if !near(controversial, controversialScore(up, down)) {
+				t.Errorf("controversial_score(%d, %d) = %v, want %v", up, down, controversial, controversialScore(up, down))
+			}
+			for _, created := range createdAt {
+				var hot float64
+				if err := db.QueryRow("SELECT hot_score($1, $2, $3)", up, down, created).Scan(&hot); err != nil {
+					t.Fatal(err)
+				}
+				if !near(hot, hotScore(up, down, created)) {
+					t.Errorf("hot_score(%d, %d, %v) = %v, want %v", up, down, created, hot, hotScore(up, down, created))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package repository
+
+import "testing"
+
+func TestEscapeHTML(t *testing.T) {
+	want := `REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(m.title, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+	if got := escapeHTML("m.title"); got != want {
+		t.Errorf("escapeHTML() = %s, want %s", got, want)
+	}
+}
+
+// TestSearchHeadlineEscapesHTML builds headlines like Search does on a postgres
+// database when TEST_POSTGRES_DSN names one.
+func TestSearchHeadlineEscapesHTML(t *testing.T) {
+	db := openTestDB(t)
+	query := `SELECT ts_headline('english', ` + escapeHTML("$1::text") + `, websearch_to_tsquery('english', $2),
+		'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')`
+	tests := []struct {
+		name   string
+		text   string
+		search string
+		want   string
+	}{
+		{name: "plain text", text: "My first post", search: "post", want: "My first <mark>post</mark>"},
+		{name: "script tag", text: "<script>alert(1)</script> post", search: "post", want: "&lt;script&gt;alert(1)&lt;/script&gt; <mark>post</mark>"},
+		{name: "attribute", text: `<img src=x onerror="alert('x')"> post`, search: "post", want: "&lt;img src=x onerror=&quot;alert(&#39;x&#39;)&quot;&gt; <mark>post</mark>"},
+		{name: "entity", text: "posts &amp; &lt;b&gt;", search: "post", want: "<mark>posts</mark> &amp;amp; &amp;lt;b&amp;gt;"},
+		{name: "mark tag", text: "<mark>fake</mark> post", search: "post", want: "&lt;mark&gt;fake&lt;/mark&gt; <mark>post</mark>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			if err := db.QueryRow(query, tt.text, tt.search).Scan(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("headline = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"time"
+)
+
+type sessionRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) domain.SessionRepository {
+	return &sessionRepositoryImpl{
+		db: db,
+	}
+}
+
+// GetActiveByUser returns the sessions of a user that can still refresh their
+// tokens, most recently seen first.
+func (s *sessionRepositoryImpl) GetActiveByUser(userID string) (*[]model.Session, error) {
+	query := `
+                SELECT s.id, s.jti, s.user_agent, s.ip, s.created_at, s.last_seen_at
+                FROM sessions s
+                WHERE s.user_id = $1 AND s.revoked_at IS NULL AND EXISTS (
+                    SELECT 1 FROM refresh_tokens rt
+                    WHERE rt.family_id = s.jti AND rt.revoked_at IS NULL AND rt.expires_at > NOW()
+                )
+                ORDER BY s.last_seen_at DESC, s.id DESC
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var sessions []model.Session
+	for rows.Next() {
+		var session model.Session
+		err := rows.Scan(
+			&session.ID,
+			&session.JTI,
+			&session.UserAgent,
+			&session.IP,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &sessions, nil
+}
+
+func (s *sessionRepositoryImpl) Create(userID, jti, userAgent, ip string) error {
+	query := "INSERT INTO sessions (user_id, jti, user_agent, ip) VALUES ($1, $2, $3, $4)"
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, query, userID, jti, userAgent, ip)
+	return err
+}
+
+func (s *sessionRepositoryImpl) Touch(jti, ip string) error {
+	query := "UPDATE sessions SET last_seen_at = CURRENT_TIMESTAMP, ip = $2 WHERE jti = $1"
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, query, jti, ip)
+	return err
+}
+
+// RevokeByID revokes a session of the user and returns its jti. it returns
+// sql.ErrNoRows when the user has no such session or it is already revoked.
+func (s *sessionRepositoryImpl) RevokeByID(userID, sessionID string) (string, error) {
+	query := `
+                UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+                WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+                RETURNING jti
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	var jti string
+	err := s.db.QueryRowContext(ctx, query, sessionID, userID).Scan(&jti)
+	return jti, err
+}
+
+func (s *sessionRepositoryImpl) RevokeByJTI(jti string) error {
+	query := "UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE jti = $1 AND revoked_at IS NULL"
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, query, jti)
+	return err
+}
+
+func (s *sessionRepositoryImpl) RevokeAllForUser(userID string) error {
+	query := "UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL"
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, query, userID)
+	return err
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"time"
+)
+
+type tagRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewTagRepository(db *sql.DB) domain.TagRepository {
+	return &tagRepositoryImpl{
+		db: db,
+	}
+}
+
+func (t *tagRepositoryImpl) GetPopular(filter *helpers.PaginateFilter) (*[]model.Tag, helpers.Metadata, error) {
+	// only visible posts are counted, tags without any of them are left out
+	query := fmt.Sprintf(
+		`
+			SELECT
+				COUNT(*) OVER() AS total_records,
+				tg.name,
+				COUNT(pt.post_id) AS post_count
+			FROM
+				tags tg
+			JOIN
+				post_tags pt ON pt.tag_id = tg.id
+			JOIN
+				posts p ON p.id = pt.post_id AND p.status = 'published' AND p.deleted_at IS NULL
+			GROUP BY
+				tg.id
+			ORDER BY
+				%s %s, tg.name ASC
+			LIMIT
+				$1
+			OFFSET
+				$2;
+        `,
+		filter.SortValue(),
+		filter.SortDirection(),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	rows, err := t.db.QueryContext(ctx, query, filter.Limit(), filter.OffSet())
+	if err != nil {
+		return nil, helpers.Metadata{}, err
+	}
+	defer rows.Close()
+	var tags []model.Tag
+	var totalRecords int
+	for rows.Next() {
+		var tag model.Tag
+		if err := rows.Scan(&totalRecords, &tag.Name, &tag.PostCount); err != nil {
+			return nil, helpers.Metadata{}, err
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, helpers.Metadata{}, err
+	}
+	metadata := helpers.CalculateMetadata(totalRecords, filter.Page, filter.PageSize)
+	return &tags, metadata, nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"time"
+)
+
+type refreshTokenRepositoryImpl struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenRepository(db *sql.DB) domain.RefreshTokenRepository {
+	return &refreshTokenRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *refreshTokenRepositoryImpl) GetByHash(tokenHash string) (*model.RefreshToken, error) {
+	query := "SELECT id, user_id, family_id, expires_at, revoked_at FROM refresh_tokens WHERE token_hash = $1"
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	var token model.RefreshToken
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.ExpiresAt,
+		&token.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *refreshTokenRepositoryImpl) Create(userID, familyID, tokenHash string, expiresAt time.Time) error {
+	query := "INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)"
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, query, userID, familyID, tokenHash, expiresAt)
+	return err
+}
+
+// Rotate revokes a refresh token and issues its successor in the same family.
+// it returns sql.ErrNoRows when the token was revoked in the meantime, which
+// means it was used twice.
+func (r *refreshTokenRepositoryImpl) Rotate(tokenID string, tokenHash string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	query := `
+                UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+                WHERE id = $1 AND revoked_at IS NULL
+                RETURNING user_id, family_id
+        `
+	var userID, familyID string
+	if err := tx.QueryRowContext(ctx, query, tokenID).Scan(&userID, &familyID); err != nil {
+		return err
+	}
+	query = "INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)"
+	if _, err := tx.ExecContext(ctx, query, userID, familyID, tokenHash, expiresAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *refreshTokenRepositoryImpl) RevokeFamily(familyID string) error {
+	query := "UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = $1 AND revoked_at IS NULL"
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, query, familyID)
+	return err
+}
+
+func (r *refreshTokenRepositoryImpl) RevokeAllForUser(userID string) error {
+	query := "UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL"
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
@@ -20,7 +20,7 @@ func NewUserRepository(db *sql.DB) domain.UserRepository {
 }
 
 func (u *userRepositoryImpl) GetByID(id string) (*model.User, error) {
-	query := "SELECT * FROM users WHERE id = $1"
+	query := "SELECT id, email, username, display_name, bio, password, created_at, email_verified_at, role, token_version, totp_secret, totp_enabled_at FROM users WHERE id = $1"
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	row := u.db.QueryRowContext(ctx, query, id)
@@ -28,24 +28,214 @@ func (u *userRepositoryImpl) GetByID(id string) (*model.User, error) {
 }
 
 func (u *userRepositoryImpl) GetByEmail(email string) (*model.User, error) {
-	query := "SELECT * FROM users WHERE email = $1"
+	query := "SELECT id, email, username, display_name, bio, password, created_at, email_verified_at, role, token_version, totp_secret, totp_enabled_at FROM users WHERE email = $1"
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	row := u.db.QueryRowContext(ctx, query, email)
 	return collectUserRow(row)
 }
 
-func (u *userRepositoryImpl) Create(user *entities.UserAuthRequest) error {
-	query := `INSERT INTO users (email, password) VALUES ($1, $2)`
+// GetByUsername finds a user by username, ignoring case. deleted accounts are
+// not found.
+func (u *userRepositoryImpl) GetByUsername(username string) (*model.User, error) {
+	query := "SELECT id, email, username, display_name, bio, password, created_at, email_verified_at, role, token_version, totp_secret, totp_enabled_at FROM users WHERE LOWER(username) = LOWER($1) AND deleted_at IS NULL"
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
-	args := []any{user.Email, user.Password}
-	_, err := u.db.ExecContext(ctx, query, args...)
-	return err
+	row := u.db.QueryRowContext(ctx, query, username)
+	return collectUserRow(row)
+}
+
+// GetProfile returns the public profile of a user by username, ignoring case.
+// deleted accounts have none.
+// only published posts count, and votes of users on their own posts are left
+// out of the karma.
+func (u *userRepositoryImpl) GetProfile(username string) (*model.UserProfile, error) {
+	query := `
+                SELECT
+                        u.id,
+                        u.username,
+                        u.display_name,
+                        u.bio,
+                        u.created_at,
+                        (SELECT COUNT(*) FROM posts p WHERE p.user_id = u.id AND p.status = 'published' AND p.deleted_at IS NULL) AS post_count,
+                        (
+                                SELECT COALESCE(SUM(v.vote), 0)
+                                FROM votes v JOIN posts p ON p.id = v.post_id
+                                WHERE p.user_id = u.id AND p.status = 'published' AND p.deleted_at IS NULL AND v.user_id <> u.id
+                        ) AS karma
+                FROM users u
+                WHERE LOWER(u.username) = LOWER($1) AND u.deleted_at IS NULL
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	var profile model.UserProfile
+	err := u.db.QueryRowContext(ctx, query, username).Scan(
+		&profile.ID,
+		&profile.Username,
+		&profile.DisplayName,
+		&profile.Bio,
+		&profile.CreatedAt,
+		&profile.PostCount,
+		&profile.Karma,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (u *userRepositoryImpl) Create(user *entities.UserRegisterRequest) (*model.User, error) {
+	query := `
+                INSERT INTO users (email, username, password) VALUES ($1, $2, $3)
+                RETURNING id, email, username, display_name, bio, password, created_at, email_verified_at, role, token_version, totp_secret, totp_enabled_at
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	args := []any{user.Email, user.Username, user.Password}
+	row := u.db.QueryRowContext(ctx, query, args...)
+	return collectUserRow(row)
+}
+
+// MarkEmailVerified verifies the email of a user, as long as it is still the
+// given address. it returns sql.ErrNoRows when there is nothing to verify.
+func (u *userRepositoryImpl) MarkEmailVerified(userID, email string) error {
+	query := `
+                UPDATE users SET email_verified_at = CURRENT_TIMESTAMP
+                WHERE id = $1 AND email = $2 AND email_verified_at IS NULL
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	result, err := u.db.ExecContext(ctx, query, userID, email)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (u *userRepositoryImpl) GetTokenVersion(userID string) (int, error) {
+	query := "SELECT token_version FROM users WHERE id = $1"
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	var tokenVersion int
+	err := u.db.QueryRowContext(ctx, query, userID).Scan(&tokenVersion)
+	return tokenVersion, err
+}
+
+// CreatePasswordResetToken stores a new reset token for the user and drops the
+// ones that were not used yet, so only the latest email works.
+func (u *userRepositoryImpl) CreatePasswordResetToken(userID, tokenHash string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	query := "DELETE FROM password_reset_tokens WHERE user_id = $1 AND used_at IS NULL"
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return err
+	}
+	query = "INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)"
+	if _, err := tx.ExecContext(ctx, query, userID, tokenHash, expiresAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ResetPassword uses up a reset token and sets the password of its user. the
+// token version is bumped, so every access token issued before stops working,
+// and the email counts as verified since the user could read the reset email.
+// it returns sql.ErrNoRows when the token is unknown, used or expired.
+func (u *userRepositoryImpl) ResetPassword(tokenHash, passwordHash string) (*model.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	query := `
+                UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP
+                WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+                RETURNING user_id
+        `
+	var userID string
+	if err := tx.QueryRowContext(ctx, query, tokenHash).Scan(&userID); err != nil {
+		return nil, err
+	}
+	query = `
+                UPDATE users
+                SET password = $2,
+                    token_version = token_version + 1,
+                    email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+                WHERE id = $1
+                RETURNING id, email, username, display_name, bio, password, created_at, email_verified_at, role, token_version, totp_secret, totp_enabled_at
+        `
+	user, err := collectUserRow(tx.QueryRowContext(ctx, query, userID, passwordHash))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpdatePassword sets the password of a user and bumps the token version, so
+// every access token issued before stops working.
+func (u *userRepositoryImpl) UpdatePassword(userID, passwordHash string) (*model.User, error) {
+	query := `
+                UPDATE users SET password = $2, token_version = token_version + 1
+                WHERE id = $1
+                RETURNING id, email, username, display_name, bio, password, created_at, email_verified_at, role, token_version, totp_secret, totp_enabled_at
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	row := u.db.QueryRowContext(ctx, query, userID, passwordHash)
+	return collectUserRow(row)
+}
+
+func (u *userRepositoryImpl) BumpTokenVersion(userID string) (int, error) {
+	query := "UPDATE users SET token_version = token_version + 1 WHERE id = $1 RETURNING token_version"
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	var tokenVersion int
+	err := u.db.QueryRowContext(ctx, query, userID).Scan(&tokenVersion)
+	return tokenVersion, err
+}
+
+// UpdateRole sets the role of a user and bumps the token version, so no access
+// token keeps carrying the old role.
+func (u *userRepositoryImpl) UpdateRole(userID, role string) (*model.User, error) {
+	query := `
+                UPDATE users SET role = $2, token_version = token_version + 1
+                WHERE id = $1
+                RETURNING id, email, username, display_name, bio, password, created_at, email_verified_at, role, token_version, totp_secret, totp_enabled_at
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	row := u.db.QueryRowContext(ctx, query, userID, role)
+	return collectUserRow(row)
+}
+
+func (u *userRepositoryImpl) UpdateProfile(userID string, profile *entities.UserProfileUpdateRequest) (*model.User, error) {
+	query := `
+                UPDATE users SET username = $2, display_name = $3, bio = $4
+                WHERE id = $1
+                RETURNING id, email, username, display_name, bio, password, created_at, email_verified_at, role, token_version, totp_secret, totp_enabled_at
+        `
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	args := []any{userID, profile.Username, profile.DisplayName, profile.Bio}
+	row := u.db.QueryRowContext(ctx, query, args...)
+	return collectUserRow(row)
 }
 
 func collectUserRow(row *sql.Row) (*model.User, error) {
 	var user model.User
-	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.CreatedAt)
+	err := row.Scan(&user.ID, &user.Email, &user.Username, &user.DisplayName, &user.Bio, &user.Password, &user.CreatedAt, &user.EmailVerifiedAt, &user.Role, &user.TokenVersion, &user.TOTPSecret, &user.TOTPEnabledAt)
 	return &user, err
 }
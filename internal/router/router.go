@@ -5,9 +5,14 @@ import (
 	_ "github.com/arshamroshannejad/task-rootext/api"
 	"github.com/arshamroshannejad/task-rootext/config"
 	"github.com/arshamroshannejad/task-rootext/internal/handler"
+	"github.com/arshamroshannejad/task-rootext/internal/keyring"
+	"github.com/arshamroshannejad/task-rootext/internal/mailer"
 	"github.com/arshamroshannejad/task-rootext/internal/middleware"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"github.com/arshamroshannejad/task-rootext/internal/policy"
 	"github.com/arshamroshannejad/task-rootext/internal/repository"
 	"github.com/arshamroshannejad/task-rootext/internal/service"
+	"github.com/arshamroshannejad/task-rootext/internal/storage"
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/redis/go-redis/v9"
@@ -17,8 +22,9 @@ import (
 	"time"
 )
 
-func SetupRoutes(db *sql.DB, redisDB *redis.Client, zapLogger *zap.Logger, cfg *config.Config) http.Handler {
+func SetupRoutes(db *sql.DB, redisDB *redis.Client, fileStorage storage.Storage, mailSender mailer.Mailer, keys *keyring.Keyring, zapLogger *zap.Logger, cfg *config.Config) http.Handler {
 	r := chi.NewRouter()
+	r.Use(middleware.RealIP(cfg))
 	r.Use(chiMiddleware.Logger)
 	r.Use(chiMiddleware.Recoverer)
 	r.Use(chiMiddleware.RedirectSlashes)
@@ -34,33 +40,138 @@ func SetupRoutes(db *sql.DB, redisDB *redis.Client, zapLogger *zap.Logger, cfg *
 		httpSwagger.DocExpansion("none"),
 		httpSwagger.DomID("swagger-ui"),
 	))
+	// stores that keep files themselves, like the local one, also serve them
+	if fileServer, ok := fileStorage.(http.Handler); ok {
+		r.Handle("/uploads/*", http.StripPrefix("/uploads", fileServer))
+	}
+	r.Get("/.well-known/jwks.json", handler.NewJWKSHandler(keys).JWKSHandler)
 	userRepository := repository.NewUserRepository(db)
-	userService := service.NewUserService(userRepository, redisDB, zapLogger, cfg)
-	userHandler := handler.NewUserHandler(userService)
+	refreshTokenRepository := repository.NewRefreshTokenRepository(db)
+	sessionRepository := repository.NewSessionRepository(db)
+	mfaRepository := repository.NewMFARepository(db)
+	accountRepository := repository.NewAccountRepository(db)
 	postRepository := repository.NewPostRepository(db)
-	postService := service.NewPostService(postRepository, redisDB, zapLogger)
-	postHandler := handler.NewPostHandler(postService)
+	postService := service.NewPostService(postRepository, redisDB, zapLogger, cfg)
+	userService := service.NewUserService(userRepository, refreshTokenRepository, sessionRepository, mfaRepository, accountRepository, postService, redisDB, mailSender, keys, zapLogger, cfg)
+	userHandler := handler.NewUserHandler(userService)
+	apiKeyRepository := repository.NewAPIKeyRepository(db)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepository, userRepository, zapLogger)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	communityRepository := repository.NewCommunityRepository(db)
+	communityService := service.NewCommunityService(communityRepository, zapLogger)
+	accessPolicy := policy.New(communityService)
+	communityHandler := handler.NewCommunityHandler(communityService, userService, accessPolicy)
+	postHandler := handler.NewPostHandler(postService, communityService, userService, accessPolicy)
+	commentRepository := repository.NewCommentRepository(db)
+	commentService := service.NewCommentService(commentRepository, zapLogger)
+	commentHandler := handler.NewCommentHandler(commentService, postService, accessPolicy)
+	attachmentRepository := repository.NewAttachmentRepository(db)
+	attachmentService := service.NewAttachmentService(attachmentRepository, postService, fileStorage, zapLogger, cfg)
+	attachmentHandler := handler.NewAttachmentHandler(attachmentService, postService, accessPolicy, cfg)
+	tagRepository := repository.NewTagRepository(db)
+	tagService := service.NewTagService(tagRepository, zapLogger)
+	tagHandler := handler.NewTagHandler(tagService)
+	requireVerifiedEmail := middleware.RequireVerifiedEmail(userService, cfg)
+	readAuth := middleware.ScopedAuth(userService, apiKeyService, model.ScopeRead, zapLogger)
+	postAuth := middleware.ScopedAuth(userService, apiKeyService, model.ScopePost, zapLogger)
+	voteAuth := middleware.ScopedAuth(userService, apiKeyService, model.ScopeVote, zapLogger)
 	apiV1Router := chi.NewRouter()
 	apiV1Router.Route("/auth", func(r chi.Router) {
 		r.Post("/register", userHandler.RegisterHandler)
 		r.Post("/login", userHandler.LoginHandler)
+		r.Post("/login/mfa", userHandler.LoginMFAHandler)
+		r.Post("/refresh", userHandler.RefreshHandler)
+		r.Post("/verify-email", userHandler.VerifyEmailHandler)
+		r.Post("/forgot-password", userHandler.ForgotPasswordHandler)
+		r.Post("/reset-password", userHandler.ResetPasswordHandler)
 		r.Group(func(r chi.Router) {
-			r.Use(middleware.JwtAuth(redisDB, zapLogger, cfg))
+			r.Use(middleware.JwtAuth(userService, zapLogger))
 			r.Post("/logout", userHandler.LogoutHandler)
+			r.Post("/logout-all", userHandler.LogoutAllHandler)
+			r.Post("/resend-verification", userHandler.ResendVerificationHandler)
+		})
+	})
+	apiV1Router.Route("/community", func(r chi.Router) {
+		r.Get("/", communityHandler.GetAllCommunitiesHandler)
+		r.Get("/{id}", communityHandler.GetCommunityHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(postAuth)
+			r.Post("/", communityHandler.CreateCommunityHandler)
+			r.Put("/{id}", communityHandler.UpdateCommunityHandler)
+			r.Post("/{id}/moderators", communityHandler.AddCommunityModeratorHandler)
+			r.Delete("/{id}/moderators/{userID}", communityHandler.RemoveCommunityModeratorHandler)
 		})
 	})
 	apiV1Router.Route("/post", func(r chi.Router) {
-		r.Get("/", postHandler.GetAllPostsHandler)
-		r.Get("/{id}", postHandler.GetPostHandler)
 		r.Group(func(r chi.Router) {
-			r.Use(middleware.JwtAuth(redisDB, zapLogger, cfg))
-			r.Post("/", postHandler.CreatePostHandler)
+			r.Use(middleware.OptionalJwtAuth(userService, apiKeyService, zapLogger))
+			r.Get("/", postHandler.GetAllPostsHandler)
+			r.Get("/search", postHandler.SearchPostsHandler)
+			r.Get("/{id}", postHandler.GetPostHandler)
+		})
+		r.Get("/{id}/revisions", postHandler.GetPostRevisionsHandler)
+		r.Get("/{id}/revisions/{rev}", postHandler.GetPostRevisionHandler)
+		r.Get("/{id}/comments", commentHandler.GetAllCommentsHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(postAuth)
+			r.With(requireVerifiedEmail).Post("/", postHandler.CreatePostHandler)
 			r.Put("/{id}", postHandler.UpdatePostHandler)
 			r.Delete("/{id}", postHandler.DeletePostHandler)
-			r.Post("/{id}/vote", postHandler.AddPostVoteHandler)
+			r.Post("/{id}/restore", postHandler.RestorePostHandler)
+			r.Post("/{id}/publish", postHandler.PublishPostHandler)
+			r.Post("/{id}/attachments", attachmentHandler.UploadAttachmentHandler)
+			r.Delete("/{id}/attachments/{attachmentID}", attachmentHandler.DeleteAttachmentHandler)
+			r.Post("/{id}/save", postHandler.SavePostHandler)
+			r.Delete("/{id}/save", postHandler.UnsavePostHandler)
+			r.Post("/{id}/comments", commentHandler.CreateCommentHandler)
+			r.Put("/{id}/comments/{commentID}", commentHandler.UpdateCommentHandler)
+			r.Delete("/{id}/comments/{commentID}", commentHandler.DeleteCommentHandler)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(voteAuth)
+			r.With(requireVerifiedEmail).Post("/{id}/vote", postHandler.AddPostVoteHandler)
 			r.Delete("/{id}/unvote", postHandler.RemovePostVoteHandler)
+			r.With(requireVerifiedEmail).Post("/{id}/comments/{commentID}/vote", commentHandler.AddCommentVoteHandler)
+			r.Delete("/{id}/comments/{commentID}/unvote", commentHandler.RemoveCommentVoteHandler)
+		})
+	})
+	apiV1Router.Get("/tags", tagHandler.GetPopularTagsHandler)
+	apiV1Router.Route("/users", func(r chi.Router) {
+		r.Get("/{username}", userHandler.GetUserProfileHandler)
+		r.With(middleware.OptionalJwtAuth(userService, apiKeyService, zapLogger)).Get("/{username}/posts", postHandler.GetUserPostsHandler)
+	})
+	apiV1Router.Route("/me", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(readAuth)
+			r.Get("/drafts", postHandler.GetMyDraftsHandler)
+			r.Get("/saved", postHandler.GetMySavedPostsHandler)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.JwtAuth(userService, zapLogger))
+			r.Put("/password", userHandler.ChangePasswordHandler)
+			r.Put("/profile", userHandler.UpdateProfileHandler)
+			r.Get("/export", userHandler.ExportMyDataHandler)
+			r.Delete("/", userHandler.DeleteMyAccountHandler)
+			r.Get("/sessions", userHandler.GetMySessionsHandler)
+			r.Delete("/sessions/{id}", userHandler.RevokeSessionHandler)
+			r.Post("/mfa/totp", userHandler.EnrollTOTPHandler)
+			r.Post("/mfa/totp/confirm", userHandler.ConfirmTOTPHandler)
+			r.Delete("/mfa/totp", userHandler.DisableTOTPHandler)
+			r.Post("/mfa/recovery-codes", userHandler.RegenerateRecoveryCodesHandler)
+			r.Get("/api-keys", apiKeyHandler.GetMyAPIKeysHandler)
+			r.Post("/api-keys", apiKeyHandler.CreateAPIKeyHandler)
+			r.Get("/api-keys/{id}", apiKeyHandler.GetMyAPIKeyHandler)
+			r.Put("/api-keys/{id}", apiKeyHandler.UpdateAPIKeyHandler)
+			r.Delete("/api-keys/{id}", apiKeyHandler.DeleteAPIKeyHandler)
 		})
 	})
+	apiV1Router.Route("/admin", func(r chi.Router) {
+		r.Use(middleware.JwtAuth(userService, zapLogger))
+		r.Use(middleware.RequireRole(model.RoleAdmin))
+		r.Put("/users/{id}/role", userHandler.SetUserRoleHandler)
+		r.Get("/users/{id}/lockout", userHandler.GetLoginLockoutHandler)
+		r.Delete("/users/{id}/lockout", userHandler.ClearLoginLockoutHandler)
+	})
 	r.Mount("/api/v1", apiV1Router)
 	return r
 }
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"go.uber.org/zap"
+	"time"
+)
+
+// ExportUserData collects the profile of a user together with everything the
+// user wrote and voted on.
+func (u *userServiceImpl) ExportUserData(userID string) (*model.UserExport, error) {
+	user, err := u.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	export := &model.UserExport{ExportedAt: time.Now().UTC(), Profile: *user}
+	if export.Posts, err = u.accountRepository.GetPosts(userID); err != nil {
+		u.zapLogger.Error("Failed to get posts of user for export", zap.Error(err))
+		return nil, err
+	}
+	if export.Comments, err = u.accountRepository.GetComments(userID); err != nil {
+		u.zapLogger.Error("Failed to get comments of user for export", zap.Error(err))
+		return nil, err
+	}
+	if export.PostVotes, err = u.accountRepository.GetPostVotes(userID); err != nil {
+		u.zapLogger.Error("Failed to get post votes of user for export", zap.Error(err))
+		return nil, err
+	}
+	if export.CommentVotes, err = u.accountRepository.GetCommentVotes(userID); err != nil {
+		u.zapLogger.Error("Failed to get comment votes of user for export", zap.Error(err))
+		return nil, err
+	}
+	return export, nil
+}
+
+// DeleteAccount anonymizes the account of a user after checking the password,
+// and a two-factor code when TOTP is enabled. every token of the user stops
+// working right away.
+func (u *userServiceImpl) DeleteAccount(userID, password, code string) error {
+	user, err := u.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := u.VerifyPassword(user.Password, password); err != nil {
+		return domain.ErrPasswordInvalid
+	}
+	if user.IsTOTPEnabled() {
+		if err := u.verifySecondFactor(user, code, true); err != nil {
+			return err
+		}
+	}
+	tokenVersion, err := u.accountRepository.Anonymize(userID)
+	if err != nil {
+		u.zapLogger.Error("Failed to delete account", zap.Error(err))
+		return err
+	}
+	// removing the votes of the user changes the scores of the posts they voted on
+	go u.postService.RefreshTopVotedCache()
+	// sessions and refresh tokens are gone with the account, access tokens are
+	// turned away by the new token version
+	if err := u.publishTokenVersion(userID, tokenVersion); err != nil {
+		return err
+	}
+	u.zapLogger.Info("Account deleted", zap.String("UserID", userID))
+	return nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/entities"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"go.uber.org/zap"
+	"slices"
+	"strings"
+)
+
+// apiKeyDisplayLength is how much of a key is kept in the clear, so users can
+// tell their keys apart.
+const apiKeyDisplayLength = 12
+
+type apiKeyServiceImpl struct {
+	apiKeyRepository domain.APIKeyRepository
+	userRepository   domain.UserRepository
+	zapLogger        *zap.Logger
+}
+
+func NewAPIKeyService(apiKeyRepository domain.APIKeyRepository, userRepository domain.UserRepository, zapLogger *zap.Logger) domain.APIKeyService {
+	return &apiKeyServiceImpl{
+		apiKeyRepository: apiKeyRepository,
+		userRepository:   userRepository,
+		zapLogger:        zapLogger,
+	}
+}
+
+func (a *apiKeyServiceImpl) GetUserAPIKeys(userID string) (*[]model.APIKey, error) {
+	apiKeys, err := a.apiKeyRepository.GetByUser(userID)
+	if err != nil {
+		a.zapLogger.Error("Failed to get api keys of user", zap.Error(err))
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+func (a *apiKeyServiceImpl) GetAPIKeyByID(userID, keyID string) (*model.APIKey, error) {
+	apiKey, err := a.apiKeyRepository.GetByID(userID, keyID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			a.zapLogger.Error("Failed to get api key with id", zap.Error(err))
+		}
+		return nil, err
+	}
+	return apiKey, nil
+}
+
+// CreateAPIKey creates a key for the user. only its hash is stored, so the
+// token of the returned key can not be shown again.
+func (a *apiKeyServiceImpl) CreateAPIKey(userID string, apiKey *entities.APIKeyCreateRequest) (*model.CreatedAPIKey, error) {
+	secret, err := helpers.RandomToken(32)
+	if err != nil {
+		a.zapLogger.Error("Failed to create api key token", zap.Error(err))
+		return nil, err
+	}
+	token := model.APIKeyPrefix + secret
+	createdKey, err := a.apiKeyRepository.Create(
+		userID,
+		apiKey.Name,
+		token[:apiKeyDisplayLength],
+		helpers.HashToken(token),
+		compactScopes(apiKey.Scopes),
+		apiKey.ExpiresAt,
+	)
+	if err != nil {
+		a.zapLogger.Error("Failed to create api key", zap.Error(err))
+		return nil, err
+	}
+	return &model.CreatedAPIKey{APIKey: *createdKey, Token: token}, nil
+}
+
+func (a *apiKeyServiceImpl) UpdateAPIKey(userID, keyID string, apiKey *entities.APIKeyUpdateRequest) (*model.APIKey, error) {
+	updatedKey, err := a.apiKeyRepository.Update(userID, keyID, apiKey.Name, compactScopes(apiKey.Scopes))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			a.zapLogger.Error("Failed to update api key", zap.Error(err))
+		}
+		return nil, err
+	}
+	return updatedKey, nil
+}
+
+func (a *apiKeyServiceImpl) DeleteAPIKey(userID, keyID string) error {
+	if err := a.apiKeyRepository.Delete(userID, keyID); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			a.zapLogger.Error("Failed to delete api key", zap.Error(err))
+		}
+		return err
+	}
+	return nil
+}
+
+// Authenticate returns the key of a token and the user it belongs to. unknown
+// and expired keys are rejected with ErrAPIKeyInvalid.
+func (a *apiKeyServiceImpl) Authenticate(token string) (*model.APIKey, *model.User, error) {
+	if !strings.HasPrefix(token, model.APIKeyPrefix) {
+		return nil, nil, domain.ErrAPIKeyInvalid
+	}
+	apiKey, err := a.apiKeyRepository.GetByHash(helpers.HashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, domain.ErrAPIKeyInvalid
+		}
+		a.zapLogger.Error("Failed to get api key with hash", zap.Error(err))
+		return nil, nil, err
+	}
+	if apiKey.IsExpired() {
+		return nil, nil, domain.ErrAPIKeyInvalid
+	}
+	user, err := a.userRepository.GetByID(apiKey.UserID)
+	if err != nil {
+		a.zapLogger.Error("Failed to get user of api key", zap.Error(err))
+		return nil, nil, err
+	}
+	if err := a.apiKeyRepository.Touch(apiKey.ID); err != nil {
+		a.zapLogger.Warn("Failed to record api key use", zap.Error(err))
+	}
+	return apiKey, user, nil
+}
+
+// compactScopes drops repeated scopes.
+func compactScopes(scopes []string) []string {
+	unique := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !slices.Contains(unique, scope) {
+			unique = append(unique, scope)
+		}
+	}
+	return unique
+}
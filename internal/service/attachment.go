@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"github.com/arshamroshannejad/task-rootext/config"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"github.com/arshamroshannejad/task-rootext/internal/storage"
+	"go.uber.org/zap"
+	"io"
+	"path/filepath"
+	"time"
+)
+
+type attachmentServiceImpl struct {
+	attachmentRepository domain.AttachmentRepository
+	postService          domain.PostService
+	storage              storage.Storage
+	zapLogger            *zap.Logger
+	cfg                  *config.Config
+}
+
+func NewAttachmentService(attachmentRepository domain.AttachmentRepository, postService domain.PostService, storage storage.Storage, zapLogger *zap.Logger, cfg *config.Config) domain.AttachmentService {
+	return &attachmentServiceImpl{
+		attachmentRepository: attachmentRepository,
+		postService:          postService,
+		storage:              storage,
+		zapLogger:            zapLogger,
+		cfg:                  cfg,
+	}
+}
+
+func (a *attachmentServiceImpl) GetAttachmentByID(attachmentID string) (*model.Attachment, error) {
+	attachment, err := a.attachmentRepository.GetByID(attachmentID)
+	if err != nil {
+		a.zapLogger.Error("Failed to get attachment with id", zap.Error(err))
+		return nil, err
+	}
+	return attachment, nil
+}
+
+func (a *attachmentServiceImpl) UploadAttachment(postID, userID, filename, contentType string, size int64, body io.Reader) (*model.Attachment, error) {
+	// a full post is turned away before the upload is stored. concurrent uploads
+	// can all pass this count, Create enforces the limit for them.
+	count, err := a.attachmentRepository.CountByPost(postID)
+	if err != nil {
+		a.zapLogger.Error("Failed to count post attachments", zap.Error(err))
+		return nil, err
+	}
+	if count >= a.cfg.Attachments.MaxPerPost {
+		return nil, domain.ErrTooManyAttachments
+	}
+	storageKey, err := newStorageKey(postID)
+	if err != nil {
+		a.zapLogger.Error("Failed to generate attachment storage key", zap.Error(err))
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := a.storage.Put(ctx, storageKey, body, size, contentType); err != nil {
+		a.zapLogger.Error("Failed to store attachment", zap.Error(err))
+		return nil, err
+	}
+	attachment := &model.Attachment{
+		PostID:      postID,
+		URL:         a.storage.URL(storageKey),
+		Filename:    filepath.Base(filename),
+		ContentType: contentType,
+		Size:        size,
+	}
+	createdAttachment, err := a.attachmentRepository.Create(attachment, userID, storageKey, a.cfg.Attachments.MaxPerPost)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = domain.ErrTooManyAttachments
+		} else {
+			a.zapLogger.Error("Failed to create attachment", zap.Error(err))
+		}
+		if err := a.storage.Delete(ctx, storageKey); err != nil {
+			a.zapLogger.Error("Failed to delete stored attachment", zap.String("Key", storageKey), zap.Error(err))
+		}
+		return nil, err
+	}
+	go a.postService.RefreshTopVotedCache()
+	return createdAttachment, nil
+}
+
+// DeleteAttachment detaches the attachment from its post right away. the
+// stored file is removed later by CleanupDetachedAttachments.
+func (a *attachmentServiceImpl) DeleteAttachment(attachmentID string) error {
+	if err := a.attachmentRepository.Detach(attachmentID); err != nil {
+		a.zapLogger.Error("Failed to delete attachment", zap.Error(err))
+		return err
+	}
+	go a.postService.RefreshTopVotedCache()
+	return nil
+}
+
+// CleanupDetachedAttachments removes the stored files of deleted attachments and
+// of attachments whose post has been purged, then their rows.
+func (a *attachmentServiceImpl) CleanupDetachedAttachments() error {
+	keys, err := a.attachmentRepository.GetDetachedKeys(100)
+	if err != nil {
+		a.zapLogger.Error("Failed to get detached attachments", zap.Error(err))
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var deleted []string
+	for attachmentID, storageKey := range keys {
+		if err := a.storage.Delete(ctx, storageKey); err != nil {
+			a.zapLogger.Error("Failed to delete stored attachment", zap.String("Key", storageKey), zap.Error(err))
+			continue
+		}
+		deleted = append(deleted, attachmentID)
+	}
+	if len(deleted) == 0 {
+		return nil
+	}
+	if err := a.attachmentRepository.DeleteDetached(deleted); err != nil {
+		a.zapLogger.Error("Failed to delete detached attachments", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// newStorageKey returns a random, unguessable key for a file of the given post.
+func newStorageKey(postID string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "posts/" + postID + "/" + hex.EncodeToString(buf), nil
+}
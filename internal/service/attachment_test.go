@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/arshamroshannejad/task-rootext/config"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"go.uber.org/zap"
+)
+
+// memoryAttachments enforces the attachment limit in Create like the postgres
+// repository does under the lock of the post row.
+type memoryAttachments struct {
+	domain.AttachmentRepository
+	mu          sync.Mutex
+	attachments map[string][]*model.Attachment
+	// beforeCreate runs after CountByPost and before Create, like concurrent
+	// uploads that all passed the count.
+	beforeCreate sync.WaitGroup
+}
+
+func (m *memoryAttachments) CountByPost(postID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.attachments[postID]), nil
+}
+
+func (m *memoryAttachments) Create(attachment *model.Attachment, userID, storageKey string, maxPerPost int) (*model.Attachment, error) {
+	m.beforeCreate.Wait()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.attachments[attachment.PostID]) >= maxPerPost {
+		return nil, sql.ErrNoRows
+	}
+	m.attachments[attachment.PostID] = append(m.attachments[attachment.PostID], attachment)
+	return attachment, nil
+}
+
+// memoryStorage keeps the keys of the stored files.
+type memoryStorage struct {
+	mu   sync.Mutex
+	keys map[string]bool
+	// stored is done once per upload that got past CountByPost.
+	stored *sync.WaitGroup
+}
+
+func (m *memoryStorage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	m.mu.Lock()
+	m.keys[key] = true
+	m.mu.Unlock()
+	m.stored.Done()
+	return nil
+}
+
+func (m *memoryStorage) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.keys, key)
+	return nil
+}
+
+func (m *memoryStorage) URL(key string) string {
+	return "/uploads/" + key
+}
+
+// refreshOnlyPosts ignores the cache refresh UploadAttachment starts.
+type refreshOnlyPosts struct {
+	domain.PostService
+}
+
+func (refreshOnlyPosts) RefreshTopVotedCache() {}
+
+func TestUploadAttachmentLimitUnderConcurrency(t *testing.T) {
+	const maxPerPost, uploads = 3, 8
+	attachments := &memoryAttachments{attachments: map[string][]*model.Attachment{
+		"post": {{PostID: "post"}},
+	}}
+	attachments.beforeCreate.Add(uploads)
+	files := &memoryStorage{keys: map[string]bool{}, stored: &attachments.beforeCreate}
+	cfg := &config.Config{Attachments: &config.Attachments{MaxPerPost: maxPerPost}}
+	service := NewAttachmentService(attachments, refreshOnlyPosts{}, files, zap.NewNop(), cfg)
+
+	errs := make(chan error, uploads)
+	var wg sync.WaitGroup
+	for range uploads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.UploadAttachment("post", "user", "a.png", "image/png", 1, strings.NewReader("a"))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var created, rejected int
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case errors.Is(err, domain.ErrTooManyAttachments):
+			rejected++
+		default:
+			t.Fatalf("UploadAttachment() error = %v", err)
+		}
+	}
+	if created != maxPerPost-1 || rejected != uploads-created {
+		t.Errorf("created %d and rejected %d uploads, want %d and %d", created, rejected, maxPerPost-1, uploads-maxPerPost+1)
+	}
+	if got := len(attachments.attachments["post"]); got != maxPerPost {
+		t.Errorf("post has %d attachments, want %d", got, maxPerPost)
+	}
+	if len(files.keys) != created {
+		t.Errorf("%d files are stored, want the %d of the created attachments", len(files.keys), created)
+	}
+	if _, err := service.UploadAttachment("post", "user", "a.png", "image/png", 1, strings.NewReader("a")); !errors.Is(err, domain.ErrTooManyAttachments) {
+		t.Errorf("UploadAttachment() on a full post error = %v, want %v", err, domain.ErrTooManyAttachments)
+	}
+}
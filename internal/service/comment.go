@@ -0,0 +1,114 @@
+package service
+
+import (
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/entities"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"go.uber.org/zap"
+)
+
+type commentServiceImpl struct {
+	commentRepository domain.CommentRepository
+	zapLogger         *zap.Logger
+}
+
+func NewCommentService(commentRepository domain.CommentRepository, zapLogger *zap.Logger) domain.CommentService {
+	return &commentServiceImpl{
+		commentRepository: commentRepository,
+		zapLogger:         zapLogger,
+	}
+}
+
+func (c *commentServiceImpl) GetAllComments(postID string, filter *helpers.PaginateFilter) (*[]model.Comment, helpers.Metadata, error) {
+	comments, metaData, err := c.commentRepository.GetAll(postID, filter)
+	if err != nil {
+		c.zapLogger.Error("Failed to get all comments", zap.Error(err))
+		return nil, helpers.Metadata{}, err
+	}
+	if len(*comments) == 0 {
+		return comments, metaData, nil
+	}
+	rootIDs := make([]string, 0, len(*comments))
+	for _, comment := range *comments {
+		rootIDs = append(rootIDs, comment.ID)
+	}
+	replies, err := c.commentRepository.GetReplies(rootIDs, filter)
+	if err != nil {
+		c.zapLogger.Error("Failed to get comment replies", zap.Error(err))
+		return nil, helpers.Metadata{}, err
+	}
+	tree := buildCommentTree(*comments, *replies)
+	return &tree, metaData, nil
+}
+
+func (c *commentServiceImpl) GetCommentByID(commentID string) (*model.Comment, error) {
+	comment, err := c.commentRepository.GetByID(commentID)
+	if err != nil {
+		c.zapLogger.Error("Failed to get comment with id", zap.Error(err))
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (c *commentServiceImpl) CreateComment(comment *entities.CommentCreateRequest, postID, userID string) (*model.Comment, error) {
+	createdComment, err := c.commentRepository.Create(comment, postID, userID)
+	if err != nil {
+		c.zapLogger.Error("Failed to create comment", zap.Error(err))
+		return nil, err
+	}
+	return createdComment, nil
+}
+
+func (c *commentServiceImpl) UpdateComment(comment *entities.CommentUpdateRequest, commentID string) (*model.Comment, error) {
+	updatedComment, err := c.commentRepository.Update(comment, commentID)
+	if err != nil {
+		c.zapLogger.Error("Failed to update comment", zap.Error(err))
+		return nil, err
+	}
+	return updatedComment, nil
+}
+
+func (c *commentServiceImpl) DeleteComment(commentID string) error {
+	if err := c.commentRepository.Delete(commentID); err != nil {
+		c.zapLogger.Error("Failed to delete comment", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (c *commentServiceImpl) AddCommentVote(commentID, userID, vote string) error {
+	if err := c.commentRepository.AddVote(commentID, userID, vote); err != nil {
+		c.zapLogger.Error("Failed to add vote on comment", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (c *commentServiceImpl) RemoveCommentVote(commentID, userID string) error {
+	if err := c.commentRepository.RemoveVote(commentID, userID); err != nil {
+		c.zapLogger.Error("Failed to remove vote on comment", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// buildCommentTree nests replies under their parents. replies arrive already
+// sorted, so appending in order keeps every level sorted the same way.
+func buildCommentTree(roots, replies []model.Comment) []model.Comment {
+	children := make(map[string][]model.Comment)
+	for _, reply := range replies {
+		children[*reply.ParentID] = append(children[*reply.ParentID], reply)
+	}
+	var attach func(comments []model.Comment) []model.Comment
+	attach = func(comments []model.Comment) []model.Comment {
+		if comments == nil {
+			return []model.Comment{}
+		}
+		for i := range comments {
+			comments[i].Replies = attach(children[comments[i].ID])
+		}
+		return comments
+	}
+	return attach(roots)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+)
+
+func comment(id string, parentID ...string) model.Comment {
+	c := model.Comment{ID: id}
+	if len(parentID) > 0 {
+		c.ParentID = &parentID[0]
+	}
+	return c
+}
+
+// shape writes a comment tree as "id[replies]", like "1[3[] 4[]] 2[]".
+func shape(t *testing.T, comments []model.Comment) string {
+	t.Helper()
+	parts := make([]string, 0, len(comments))
+	for _, c := range comments {
+		// empty replies are sent as [] rather than null
+		if c.Replies == nil {
+			t.Errorf("comment %s has nil replies", c.ID)
+		}
+		parts = append(parts, c.ID+"["+shape(t, c.Replies)+"]")
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestBuildCommentTree(t *testing.T) {
+	tests := []struct {
+		name    string
+		roots   []model.Comment
+		replies []model.Comment
+		want    string
+	}{
+		{name: "no comments"},
+		{name: "no replies", roots: []model.Comment{comment("1"), comment("2")}, want: "1[] 2[]"},
+		{
+			name:    "replies keep their order on every level",
+			roots:   []model.Comment{comment("2"), comment("1")},
+			replies: []model.Comment{comment("4", "1"), comment("3", "1"), comment("6", "2"), comment("5", "4"), comment("8", "5"), comment("7", "4")},
+			want:    "2[6[]] 1[4[5[8[]] 7[]] 3[]]",
+		},
+		{
+			name:    "reply listed before its parent",
+			roots:   []model.Comment{comment("1")},
+			replies: []model.Comment{comment("5", "4"), comment("4", "1")},
+			want:    "1[4[5[]]]",
+		},
+		{
+			name:    "replies of comments on other pages are left out",
+			roots:   []model.Comment{comment("1")},
+			replies: []model.Comment{comment("9", "8"), comment("3", "1")},
+			want:    "1[3[]]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildCommentTree(tt.roots, tt.replies)
+			if tree == nil {
+				t.Fatal("buildCommentTree returned nil, want an empty list")
+			}
+			if got := shape(t, tree); got != tt.want {
+				t.Errorf("buildCommentTree = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
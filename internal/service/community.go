@@ -0,0 +1,91 @@
+package service
+
+import (
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/entities"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"go.uber.org/zap"
+)
+
+type communityServiceImpl struct {
+	communityRepository domain.CommunityRepository
+	zapLogger           *zap.Logger
+}
+
+func NewCommunityService(communityRepository domain.CommunityRepository, zapLogger *zap.Logger) domain.CommunityService {
+	return &communityServiceImpl{
+		communityRepository: communityRepository,
+		zapLogger:           zapLogger,
+	}
+}
+
+func (c *communityServiceImpl) GetAllCommunities(filter *helpers.PaginateFilter) (*[]model.Community, helpers.Metadata, error) {
+	communities, metaData, err := c.communityRepository.GetAll(filter)
+	if err != nil {
+		c.zapLogger.Error("Failed to get all communities", zap.Error(err))
+		return nil, helpers.Metadata{}, err
+	}
+	return communities, metaData, nil
+}
+
+func (c *communityServiceImpl) GetCommunityByID(communityID string) (*model.Community, error) {
+	community, err := c.communityRepository.GetByID(communityID)
+	if err != nil {
+		c.zapLogger.Error("Failed to get community with id", zap.Error(err))
+		return nil, err
+	}
+	return community, nil
+}
+
+func (c *communityServiceImpl) GetCommunityByName(name string) (*model.Community, error) {
+	community, err := c.communityRepository.GetByName(name)
+	if err != nil {
+		c.zapLogger.Error("Failed to get community with name", zap.Error(err))
+		return nil, err
+	}
+	return community, nil
+}
+
+func (c *communityServiceImpl) CreateCommunity(community *entities.CommunityCreateRequest, userID string) (*model.Community, error) {
+	createdCommunity, err := c.communityRepository.Create(community, userID)
+	if err != nil {
+		c.zapLogger.Error("Failed to create community", zap.Error(err))
+		return nil, err
+	}
+	return createdCommunity, nil
+}
+
+func (c *communityServiceImpl) UpdateCommunity(community *entities.CommunityUpdateRequest, communityID string) (*model.Community, error) {
+	updatedCommunity, err := c.communityRepository.Update(community, communityID)
+	if err != nil {
+		c.zapLogger.Error("Failed to update community", zap.Error(err))
+		return nil, err
+	}
+	return updatedCommunity, nil
+}
+
+func (c *communityServiceImpl) IsCommunityModerator(communityID, userID string) (bool, error) {
+	isModerator, err := c.communityRepository.IsModerator(communityID, userID)
+	if err != nil {
+		c.zapLogger.Error("Failed to check community moderator", zap.Error(err))
+		return false, err
+	}
+	return isModerator, nil
+}
+
+func (c *communityServiceImpl) AddCommunityModerator(communityID, userID string) error {
+	if err := c.communityRepository.AddModerator(communityID, userID); err != nil {
+		c.zapLogger.Error("Failed to add community moderator", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (c *communityServiceImpl) RemoveCommunityModerator(communityID, userID string) error {
+	if err := c.communityRepository.RemoveModerator(communityID, userID); err != nil {
+		c.zapLogger.Error("Failed to remove community moderator", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBackoffDoublings caps the exponent of the login backoff, the delay is
+// capped by the lockout duration long before that anyway.
+const maxBackoffDoublings = 16
+
+// dummyPasswordHash is compared with when nobody has the email of a login, so
+// the request takes as long as one with a wrong password.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("password of a user that does not exist"), bcrypt.DefaultCost)
+
+// Authenticate checks the credentials of a login. an unknown email and a wrong
+// password both end with a bcrypt comparison and ErrInvalidCredentials, so
+// neither the response nor its timing tells whether an account exists.
+// failures are counted per email and per address. every failure of an email
+// doubles the wait before its next attempt, too many lock it out for a while,
+// and too many from one address block the address. attempts that are refused
+// return a LoginThrottledError.
+func (u *userServiceImpl) Authenticate(email, password, ip string) (*model.User, error) {
+	account := loginAccount(email)
+	if err := u.checkLoginThrottle(account, ip); err != nil {
+		return nil, err
+	}
+	user, err := u.userRepository.GetByEmail(email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		u.zapLogger.Error("Failed to get user with email", zap.Error(err))
+		return nil, err
+	}
+	passwordHash := dummyPasswordHash
+	if err == nil {
+		passwordHash = []byte(user.Password)
+	}
+	if compareErr := bcrypt.CompareHashAndPassword(passwordHash, []byte(password)); err != nil || compareErr != nil {
+		u.recordLoginFailure(account, ip)
+		return nil, domain.ErrInvalidCredentials
+	}
+	if err := u.redisDB.Del(context.Background(), "login_failures:"+account).Err(); err != nil {
+		u.zapLogger.Error("Failed to reset login failures", zap.Error(err))
+	}
+	return user, nil
+}
+
+// GetLoginLockout returns the failed login attempts of a user and whether the
+// account is locked out.
+func (u *userServiceImpl) GetLoginLockout(userID string) (*model.LoginLockout, error) {
+	user, err := u.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	account := loginAccount(user.Email)
+	ctx := context.Background()
+	lockout := new(model.LoginLockout)
+	failures, lastFailure, err := u.getLoginFailures(account)
+	if err != nil {
+		return nil, err
+	}
+	lockout.FailedAttempts = failures
+	if !lastFailure.IsZero() {
+		lockout.LastFailureAt = &lastFailure
+	}
+	lockTTL, err := u.redisDB.TTL(ctx, "login_lockout:"+account).Result()
+	if err != nil {
+		u.zapLogger.Error("Failed to get login lockout from redis", zap.Error(err))
+		return nil, err
+	}
+	if lockTTL > 0 {
+		lockedUntil := time.Now().Add(lockTTL)
+		lockout.Locked = true
+		lockout.LockedUntil = &lockedUntil
+		// the failures that caused the lockout are kept with it
+		if lockedFailures, err := u.redisDB.Get(ctx, "login_lockout:"+account).Int(); err == nil {
+			lockout.FailedAttempts += lockedFailures
+		}
+	}
+	return lockout, nil
+}
+
+// ClearLoginLockout unlocks an account and forgets its failed login attempts.
+func (u *userServiceImpl) ClearLoginLockout(userID string) error {
+	user, err := u.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	account := loginAccount(user.Email)
+	if err := u.redisDB.Del(context.Background(), "login_failures:"+account, "login_lockout:"+account).Err(); err != nil {
+		u.zapLogger.Error("Failed to clear login lockout", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (u *userServiceImpl) checkLoginThrottle(account, ip string) error {
+	ctx := context.Background()
+	lockTTL, err := u.redisDB.TTL(ctx, "login_lockout:"+account).Result()
+	if err != nil {
+		u.zapLogger.Error("Failed to get login lockout from redis", zap.Error(err))
+		return err
+	}
+	if lockTTL > 0 {
+		return &domain.LoginThrottledError{RetryAfter: lockTTL}
+	}
+	ipKey := "login_failures_ip:" + ip
+	ipFailures, err := u.redisDB.Get(ctx, ipKey).Int()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		u.zapLogger.Error("Failed to get login failures of address from redis", zap.Error(err))
+		return err
+	}
+	if ipFailures >= u.cfg.Auth.LoginIPMaxAttempts {
+		retryAfter, err := u.redisDB.TTL(ctx, ipKey).Result()
+		if err != nil || retryAfter <= 0 {
+			retryAfter = u.cfg.Auth.LoginAttemptWindow
+		}
+		return &domain.LoginThrottledError{RetryAfter: retryAfter}
+	}
+	failures, lastFailure, err := u.getLoginFailures(account)
+	if err != nil {
+		return err
+	}
+	if failures == 0 {
+		return nil
+	}
+	backoff := u.cfg.Auth.LoginBackoffBase << min(failures-1, maxBackoffDoublings)
+	backoff = min(backoff, u.cfg.Auth.LoginLockoutDuration)
+	if retryAfter := time.Until(lastFailure.Add(backoff)); retryAfter > 0 {
+		return &domain.LoginThrottledError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login of an account and an address. the
+// counters expire after the attempt window without failures. an account with
+// too many failures is locked out and starts over afterwards.
+func (u *userServiceImpl) recordLoginFailure(account, ip string) {
+	ctx := context.Background()
+	key := "login_failures:" + account
+	ipKey := "login_failures_ip:" + ip
+	pipe := u.redisDB.TxPipeline()
+	failures := pipe.HIncrBy(ctx, key, "count", 1)
+	pipe.HSet(ctx, key, "last", time.Now().UnixMilli())
+	pipe.Expire(ctx, key, u.cfg.Auth.LoginAttemptWindow)
+	pipe.Incr(ctx, ipKey)
+	pipe.Expire(ctx, ipKey, u.cfg.Auth.LoginAttemptWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		u.zapLogger.Error("Failed to record login failure", zap.Error(err))
+		return
+	}
+	if int(failures.Val()) < u.cfg.Auth.LoginMaxAttempts {
+		return
+	}
+	u.zapLogger.Warn("Account locked out after failed logins", zap.String("Email", account), zap.String("IP", ip))
+	pipe = u.redisDB.TxPipeline()
+	pipe.Set(ctx, "login_lockout:"+account, failures.Val(), u.cfg.Auth.LoginLockoutDuration)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		u.zapLogger.Error("Failed to lock out account", zap.Error(err))
+	}
+}
+
+func (u *userServiceImpl) getLoginFailures(account string) (int, time.Time, error) {
+	state, err := u.redisDB.HGetAll(context.Background(), "login_failures:"+account).Result()
+	if err != nil {
+		u.zapLogger.Error("Failed to get login failures from redis", zap.Error(err))
+		return 0, time.Time{}, err
+	}
+	failures, _ := strconv.Atoi(state["count"])
+	lastFailure, err := strconv.ParseInt(state["last"], 10, 64)
+	if err != nil {
+		return failures, time.Time{}, nil
+	}
+	return failures, time.UnixMilli(lastFailure), nil
+}
+
+// loginAccount is the key failed logins are counted under. it does not need
+// an account to exist, so unknown emails are throttled the same way.
+func loginAccount(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	testAccount = "james@gmail.com"
+	testIP      = "203.0.113.9"
+)
+
+// failLogins stores failed logins of the test account, the last one since ago.
+func (f *refreshFixture) failLogins(failures int, since time.Duration) {
+	f.redis.hashes["login_failures:"+testAccount] = map[string]string{
+		"count": strconv.Itoa(failures),
+		"last":  strconv.FormatInt(time.Now().Add(-since).UnixMilli(), 10),
+	}
+}
+
+// retryAfter returns how long err asks to wait, zero when it is nil.
+func retryAfter(t *testing.T, err error) time.Duration {
+	t.Helper()
+	if err == nil {
+		return 0
+	}
+	var throttled *domain.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("error = %v, want a LoginThrottledError", err)
+	}
+	return throttled.RetryAfter
+}
+
+func assertRetryAfter(t *testing.T, err error, want time.Duration) {
+	t.Helper()
+	got := retryAfter(t, err)
+	if want == 0 && got != 0 || (got-want).Abs() > 500*time.Millisecond {
+		t.Errorf("retry after = %v, want %v", got, want)
+	}
+}
+
+func TestCheckLoginThrottle(t *testing.T) {
+	tests := []struct {
+		name string
+		// failures of the account, the last one sinceLast ago
+		failures  int
+		sinceLast time.Duration
+		lockout   time.Duration
+		// failures of the address, which expire after ipTTL
+		ipFailures int
+		ipTTL      time.Duration
+		want       time.Duration
+	}{
+		{name: "no failures"},
+		{name: "first failure waits the base", failures: 1, want: time.Second},
+		{name: "first failure after the base", failures: 1, sinceLast: 1500 * time.Millisecond},
+		{name: "backoff doubles", failures: 3, sinceLast: time.Second, want: 3 * time.Second},
+		{name: "doubled backoff has passed", failures: 4, sinceLast: 9 * time.Second},
+		{name: "backoff is capped at the lockout duration", failures: 11, sinceLast: 10 * time.Minute, want: 5 * time.Minute},
+		{name: "capped backoff has passed", failures: 11, sinceLast: 15*time.Minute + time.Second},
+		{name: "many failures keep the capped backoff", failures: 100, sinceLast: time.Minute, want: 14 * time.Minute},
+		{name: "account locked out", lockout: 10 * time.Minute, want: 10 * time.Minute},
+		{name: "address at the cap", ipFailures: 20, ipTTL: 5 * time.Minute, want: 5 * time.Minute},
+		{name: "address below the cap", ipFailures: 19, ipTTL: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newRefreshFixture(t)
+			if tt.failures > 0 {
+				f.failLogins(tt.failures, tt.sinceLast)
+			}
+			if tt.lockout > 0 {
+				f.redis.data["login_lockout:"+testAccount] = "5"
+				f.redis.expire("login_lockout:"+testAccount, tt.lockout)
+			}
+			if tt.ipFailures > 0 {
+				f.redis.data["login_failures_ip:"+testIP] = strconv.Itoa(tt.ipFailures)
+				f.redis.expire("login_failures_ip:"+testIP, tt.ipTTL)
+			}
+			assertRetryAfter(t, f.service.checkLoginThrottle(testAccount, testIP), tt.want)
+		})
+	}
+}
+
+func TestRecordLoginFailureLocksOut(t *testing.T) {
+	f := newRefreshFixture(t)
+	for i := 1; i < 5; i++ {
+		f.service.recordLoginFailure(testAccount, testIP)
+	}
+	failures, lastFailure, err := f.service.getLoginFailures(testAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failures != 4 || time.Since(lastFailure) > time.Second {
+		t.Errorf("failures = %d, last at %v, want 4 just now", failures, lastFailure)
+	}
+	if _, locked := f.redis.data["login_lockout:"+testAccount]; locked {
+		t.Fatal("locked out before the fifth failure")
+	}
+	// the fifth failure hands the count over to the lockout
+	f.service.recordLoginFailure(testAccount, testIP)
+	if got := f.redis.data["login_lockout:"+testAccount]; got != "5" {
+		t.Errorf("lockout holds %q failures, want 5", got)
+	}
+	if ttl := time.Until(f.redis.expires["login_lockout:"+testAccount]); (ttl - 15*time.Minute).Abs() > time.Second {
+		t.Errorf("lockout expires in %v, want 15m", ttl)
+	}
+	if _, ok := f.redis.hashes["login_failures:"+testAccount]; ok {
+		t.Error("failures are kept after the lockout")
+	}
+	if got := f.redis.data["login_failures_ip:"+testIP]; got != "5" {
+		t.Errorf("failures of the address = %q, want 5", got)
+	}
+	if ttl := time.Until(f.redis.expires["login_failures_ip:"+testIP]); (ttl - 15*time.Minute).Abs() > time.Second {
+		t.Errorf("failures of the address expire in %v, want 15m", ttl)
+	}
+	assertRetryAfter(t, f.service.checkLoginThrottle(testAccount, testIP), 15*time.Minute)
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		// prepare runs before the login
+		prepare func(f *refreshFixture)
+		wantErr error
+		// throttled is whether the login is refused before the password is
+		// checked
+		throttled bool
+		// failures is what the account has counted after the login
+		failures int
+	}{
+		{name: "right password", email: testAccount, password: "correct horse"},
+		{
+			name:     "right password resets the failures",
+			email:    testAccount,
+			password: "correct horse",
+			prepare:  func(f *refreshFixture) { f.failLogins(2, 3*time.Second) },
+		},
+		{name: "wrong password", email: testAccount, password: "battery staple", wantErr: domain.ErrInvalidCredentials, failures: 1},
+		{name: "unknown email", email: "nobody@gmail.com", password: "correct horse", wantErr: domain.ErrInvalidCredentials},
+		{
+			name:     "failures of another spelling of the email",
+			email:    " James@Gmail.com ",
+			password: "battery staple",
+			wantErr:  domain.ErrInvalidCredentials,
+			failures: 1,
+		},
+		{
+			name:      "right password during the backoff",
+			email:     testAccount,
+			password:  "correct horse",
+			prepare:   func(f *refreshFixture) { f.failLogins(2, 0) },
+			throttled: true,
+			failures:  2,
+		},
+		{
+			name:     "right password while locked out",
+			email:    testAccount,
+			password: "correct horse",
+			prepare: func(f *refreshFixture) {
+				f.redis.data["login_lockout:"+testAccount] = "5"
+				f.redis.expire("login_lockout:"+testAccount, time.Minute)
+			},
+			throttled: true,
+		},
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newRefreshFixture(t)
+			f.users.users["7"].Password = string(hash)
+			if tt.prepare != nil {
+				tt.prepare(f)
+			}
+			user, err := f.service.Authenticate(tt.email, tt.password, testIP)
+			switch {
+			case tt.throttled:
+				if retryAfter(t, err) <= 0 {
+					t.Fatalf("Authenticate error = %v, want a throttled login", err)
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Fatalf("Authenticate error = %v, want %v", err, tt.wantErr)
+			case err == nil && user.ID != "7":
+				t.Errorf("Authenticate = user %s, want 7", user.ID)
+			}
+			failures, _, err := f.service.getLoginFailures(testAccount)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if failures != tt.failures {
+				t.Errorf("failures = %d, want %d", failures, tt.failures)
+			}
+		})
+	}
+}
+
+func TestAuthenticateUnknownEmail(t *testing.T) {
+	f := newRefreshFixture(t)
+	_, err := f.service.Authenticate("nobody@gmail.com", "correct horse", testIP)
+	if !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Fatalf("Authenticate error = %v, want %v", err, domain.ErrInvalidCredentials)
+	}
+	// unknown emails are throttled like accounts, so they can not be told apart
+	failures, _, err := f.service.getLoginFailures("nobody@gmail.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failures != 1 {
+		t.Errorf("failures of the unknown email = %d, want 1", failures)
+	}
+	assertRetryAfter(t, f.service.checkLoginThrottle("nobody@gmail.com", testIP), time.Second)
+	// and take as long as a wrong password, because they are compared with a
+	// dummy hash of the same cost
+	start := time.Now()
+	_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte("correct horse"))
+	compare := time.Since(start)
+	start = time.Now()
+	if _, err := f.service.Authenticate("someone@gmail.com", "correct horse", "198.51.100.4"); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Fatalf("Authenticate error = %v, want %v", err, domain.ErrInvalidCredentials)
+	}
+	if elapsed := time.Since(start); elapsed < compare/4 {
+		t.Errorf("login of an unknown email took %v, a bcrypt comparison takes %v", elapsed, compare)
+	}
+}
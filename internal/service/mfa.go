@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	// mfaPendingPurpose is the audience of the tokens of the first login step.
+	mfaPendingPurpose = "mfa_pending"
+	// recoveryCodeCount is how many recovery codes a user gets at once.
+	recoveryCodeCount = 10
+)
+
+// mfaPendingClaims is the payload of the token the first login step returns.
+// the subject is the user and the id a nonce that makes it single-use. the
+// token version is part of it, so a password change in between makes it stop
+// working.
+type mfaPendingClaims struct {
+	TokenVersion int `json:"tv"`
+	jwt.RegisteredClaims
+}
+
+// BeginTOTPEnrollment creates a new TOTP secret for the user. it only takes
+// effect once ConfirmTOTPEnrollment gets a code generated from it.
+func (u *userServiceImpl) BeginTOTPEnrollment(userID string) (*model.TOTPEnrollment, error) {
+	user, err := u.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsTOTPEnabled() {
+		return nil, domain.ErrTOTPAlreadyEnabled
+	}
+	secret, err := helpers.GenerateTOTPSecret()
+	if err != nil {
+		u.zapLogger.Error("Failed to generate totp secret", zap.Error(err))
+		return nil, err
+	}
+	if err := u.mfaRepository.SetPendingTOTPSecret(userID, secret); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrTOTPAlreadyEnabled
+		}
+		u.zapLogger.Error("Failed to store totp secret", zap.Error(err))
+		return nil, err
+	}
+	return &model.TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: helpers.TOTPProvisioningURI(u.cfg.Auth.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables TOTP once the user proves their authenticator
+// app works, and returns the recovery codes. they are only shown this once.
+func (u *userServiceImpl) ConfirmTOTPEnrollment(userID, code string) ([]string, error) {
+	user, err := u.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsTOTPEnabled() {
+		return nil, domain.ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == nil {
+		return nil, domain.ErrTOTPNotEnrolling
+	}
+	if err := u.verifySecondFactor(user, code, false); err != nil {
+		return nil, err
+	}
+	codes, codeHashes, err := generateRecoveryCodes()
+	if err != nil {
+		u.zapLogger.Error("Failed to generate recovery codes", zap.Error(err))
+		return nil, err
+	}
+	if err := u.mfaRepository.EnableTOTP(userID, codeHashes); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrTOTPNotEnrolling
+		}
+		u.zapLogger.Error("Failed to enable totp", zap.Error(err))
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns TOTP off after checking a TOTP or recovery code, so a
+// stolen access token alone can not remove the second factor.
+func (u *userServiceImpl) DisableTOTP(userID, code string) error {
+	user, err := u.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.IsTOTPEnabled() {
+		return domain.ErrTOTPNotEnabled
+	}
+	if err := u.verifySecondFactor(user, code, true); err != nil {
+		return err
+	}
+	if err := u.mfaRepository.DisableTOTP(userID); err != nil {
+		u.zapLogger.Error("Failed to disable totp", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user, used or not.
+func (u *userServiceImpl) RegenerateRecoveryCodes(userID, code string) ([]string, error) {
+	user, err := u.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsTOTPEnabled() {
+		return nil, domain.ErrTOTPNotEnabled
+	}
+	if err := u.verifySecondFactor(user, code, true); err != nil {
+		return nil, err
+	}
+	codes, codeHashes, err := generateRecoveryCodes()
+	if err != nil {
+		u.zapLogger.Error("Failed to generate recovery codes", zap.Error(err))
+		return nil, err
+	}
+	if err := u.mfaRepository.ReplaceRecoveryCodes(userID, codeHashes); err != nil {
+		u.zapLogger.Error("Failed to replace recovery codes", zap.Error(err))
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CreateMFAChallenge signs the short-lived token that stands in for the
+// password during the second login step.
+func (u *userServiceImpl) CreateMFAChallenge(user *model.User) (*model.MFAChallenge, error) {
+	nonce, err := helpers.RandomToken(16)
+	if err != nil {
+		u.zapLogger.Error("Failed to create mfa token nonce", zap.Error(err))
+		return nil, err
+	}
+	claims := mfaPendingClaims{
+		TokenVersion: user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.ID,
+			Audience:  jwt.ClaimStrings{mfaPendingPurpose},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(u.cfg.Auth.MFAPendingTTL)),
+			ID:        nonce,
+		},
+	}
+	token, err := u.keys.Sign(claims)
+	if err != nil {
+		u.zapLogger.Error("Failed to sign mfa token", zap.Error(err))
+		return nil, err
+	}
+	return &model.MFAChallenge{MFARequired: true, MFAToken: token}, nil
+}
+
+// CompleteMFALogin exchanges the token of the first login step and a TOTP or
+// recovery code for a new session. every token can only be used once.
+func (u *userServiceImpl) CompleteMFALogin(mfaToken, code, userAgent, ip string) (*model.TokenPair, error) {
+	var claims mfaPendingClaims
+	if err := u.keys.ParseFor(mfaPendingPurpose, mfaToken, &claims); err != nil {
+		return nil, domain.ErrMFATokenInvalid
+	}
+	remaining := time.Until(claims.ExpiresAt.Time)
+	if remaining <= 0 {
+		return nil, domain.ErrMFATokenInvalid
+	}
+	user, err := u.userRepository.GetByID(claims.Subject)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrMFATokenInvalid
+		}
+		u.zapLogger.Error("Failed to get user with id", zap.Error(err))
+		return nil, err
+	}
+	if user.TokenVersion != claims.TokenVersion || !user.IsTOTPEnabled() {
+		return nil, domain.ErrMFATokenInvalid
+	}
+	if err := u.verifySecondFactor(user, code, true); err != nil {
+		return nil, err
+	}
+	fresh, err := u.redisDB.SetNX(context.Background(), "mfa_pending_used:"+claims.ID, 1, remaining).Result()
+	if err != nil {
+		u.zapLogger.Error("Failed to mark mfa token as used", zap.Error(err))
+		return nil, err
+	}
+	if !fresh {
+		return nil, domain.ErrMFATokenInvalid
+	}
+	return u.CreateTokenPair(user, userAgent, ip)
+}
+
+// verifySecondFactor checks a TOTP code of the user, or a recovery code which
+// is used up when allowRecovery is set. failures are counted per user and once
+// there are too many every code is rejected until the attempt window passes,
+// so six digit codes can not be guessed.
+func (u *userServiceImpl) verifySecondFactor(user *model.User, code string, allowRecovery bool) error {
+	ctx := context.Background()
+	key := "mfa_failures:" + user.ID
+	failures, err := u.redisDB.Get(ctx, key).Int()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		u.zapLogger.Error("Failed to get mfa failures from redis", zap.Error(err))
+		return err
+	}
+	if failures >= u.cfg.Auth.MFAMaxAttempts {
+		return domain.ErrMFAAttemptsExceeded
+	}
+	valid, err := u.checkSecondFactor(user, strings.TrimSpace(code), allowRecovery)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		u.countMFAFailure(key)
+		return domain.ErrTOTPCodeInvalid
+	}
+	u.redisDB.Del(ctx, key)
+	return nil
+}
+
+// countMFAFailure adds a failure to the counter, the attempt window starts with
+// the first failure.
+func (u *userServiceImpl) countMFAFailure(key string) {
+	ctx := context.Background()
+	failures, err := u.redisDB.Incr(ctx, key).Result()
+	if err != nil {
+		u.zapLogger.Error("Failed to count mfa failure", zap.Error(err))
+		return
+	}
+	if failures == 1 {
+		if err := u.redisDB.Expire(ctx, key, u.cfg.Auth.MFAAttemptWindow).Err(); err != nil {
+			u.zapLogger.Error("Failed to set mfa failure window", zap.Error(err))
+		}
+	}
+}
+
+func (u *userServiceImpl) checkSecondFactor(user *model.User, code string, allowRecovery bool) (bool, error) {
+	if step, ok := helpers.ValidateTOTP(*user.TOTPSecret, code, time.Now()); ok {
+		// a code is accepted for a few time steps, remember it so it can not be replayed
+		key := fmt.Sprintf("totp_used:%s:%d", user.ID, step)
+		fresh, err := u.redisDB.SetNX(context.Background(), key, 1, 3*helpers.TOTPPeriod*time.Second).Result()
+		if err != nil {
+			u.zapLogger.Error("Failed to mark totp code as used", zap.Error(err))
+			return false, err
+		}
+		return fresh, nil
+	}
+	if !allowRecovery {
+		return false, nil
+	}
+	err := u.mfaRepository.UseRecoveryCode(user.ID, helpers.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		u.zapLogger.Error("Failed to use recovery code", zap.Error(err))
+		return false, err
+	}
+	return true, nil
+}
+
+// generateRecoveryCodes returns new recovery codes, formatted like abcd-efgh,
+// and the hashes of them which are what gets stored.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	codeHashes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		codes = append(codes, raw[:4]+"-"+raw[4:])
+		codeHashes = append(codeHashes, helpers.HashToken(raw))
+	}
+	return codes, codeHashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"github.com/arshamroshannejad/task-rootext/config"
 	"github.com/arshamroshannejad/task-rootext/internal/domain"
 	"github.com/arshamroshannejad/task-rootext/internal/entities"
 	"github.com/arshamroshannejad/task-rootext/internal/helpers"
@@ -16,18 +18,20 @@ type postServiceImpl struct {
 	postRepository domain.PostRepository
 	redisDB        *redis.Client
 	zapLogger      *zap.Logger
+	cfg            *config.Config
 }
 
-func NewPostService(postRepository domain.PostRepository, redisDB *redis.Client, zapLogger *zap.Logger) domain.PostService {
+func NewPostService(postRepository domain.PostRepository, redisDB *redis.Client, zapLogger *zap.Logger, cfg *config.Config) domain.PostService {
 	return &postServiceImpl{
 		postRepository: postRepository,
 		redisDB:        redisDB,
 		zapLogger:      zapLogger,
+		cfg:            cfg,
 	}
 }
 
-func (p *postServiceImpl) GetAllPosts(filter *helpers.PaginateFilter) (*[]model.Post, helpers.Metadata, error) {
-	if filter.Page == 1 && filter.Sort == "-vote_count" {
+func (p *postServiceImpl) GetAllPosts(filter *helpers.PaginateFilter, postFilter *entities.PostFilter) (*[]model.Post, helpers.Metadata, error) {
+	if filter.Page == 1 && filter.Sort == "-vote_count" && !filter.UseCursor && postFilter.IsEmpty() {
 		cachedData, err := p.redisDB.Get(context.Background(), "top_5_posts").Result()
 		if err == nil {
 			var cachedResponse struct {
@@ -40,7 +44,7 @@ func (p *postServiceImpl) GetAllPosts(filter *helpers.PaginateFilter) (*[]model.
 			}
 		}
 	}
-	posts, metaData, err := p.postRepository.GetAll(filter)
+	posts, metaData, err := p.postRepository.GetAll(filter, postFilter)
 	if err != nil {
 		p.zapLogger.Error("Failed to get all posts", zap.Error(err))
 		return nil, helpers.Metadata{}, err
@@ -57,6 +61,28 @@ func (p *postServiceImpl) GetPostByID(postID string) (*model.Post, error) {
 	return post, err
 }
 
+// GetPublishedPostByID hides drafts and scheduled posts behind sql.ErrNoRows,
+// so public endpoints treat them exactly like missing posts.
+func (p *postServiceImpl) GetPublishedPostByID(postID string) (*model.Post, error) {
+	post, err := p.GetPostByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if !post.IsPublished() {
+		return nil, sql.ErrNoRows
+	}
+	return post, nil
+}
+
+func (p *postServiceImpl) GetDeletedPostByID(postID string) (*model.Post, error) {
+	post, err := p.postRepository.GetDeletedByID(postID, p.cfg.Posts.RestoreWindow)
+	if err != nil {
+		p.zapLogger.Error("Failed to get deleted post with id", zap.Error(err))
+		return nil, err
+	}
+	return post, err
+}
+
 func (p *postServiceImpl) GetPostByTitle(title string) (*model.Post, error) {
 	post, err := p.postRepository.GetByTitle(title)
 	if err != nil {
@@ -66,7 +92,60 @@ func (p *postServiceImpl) GetPostByTitle(title string) (*model.Post, error) {
 	return post, err
 }
 
-func (p *postServiceImpl) CreatePost(post *entities.PostCreateUpdateRequest, userID string) (*model.Post, error) {
+func (p *postServiceImpl) SearchPosts(query string, filter *helpers.PaginateFilter, postFilter *entities.PostFilter) (*[]model.PostSearchResult, helpers.Metadata, error) {
+	results, metaData, err := p.postRepository.Search(query, filter, postFilter)
+	if err != nil {
+		p.zapLogger.Error("Failed to search posts", zap.Error(err))
+		return nil, helpers.Metadata{}, err
+	}
+	return results, metaData, nil
+}
+
+func (p *postServiceImpl) GetUserDrafts(userID string, filter *helpers.PaginateFilter) (*[]model.Post, helpers.Metadata, error) {
+	posts, metaData, err := p.postRepository.GetDraftsByUser(userID, filter)
+	if err != nil {
+		p.zapLogger.Error("Failed to get user drafts", zap.Error(err))
+		return nil, helpers.Metadata{}, err
+	}
+	return posts, metaData, nil
+}
+
+func (p *postServiceImpl) GetUserSavedPosts(userID string, filter *helpers.PaginateFilter) (*[]model.Post, helpers.Metadata, error) {
+	posts, metaData, err := p.postRepository.GetSavedByUser(userID, filter)
+	if err != nil {
+		p.zapLogger.Error("Failed to get user saved posts", zap.Error(err))
+		return nil, helpers.Metadata{}, err
+	}
+	return posts, metaData, nil
+}
+
+// ApplyViewerState fills the per-user fields of the posts for the given user.
+// it runs after the posts are loaded, so cached listings stay shared between users.
+func (p *postServiceImpl) ApplyViewerState(userID string, posts ...*model.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	postIDs := make([]string, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID
+	}
+	states, err := p.postRepository.GetViewerStates(userID, postIDs)
+	if err != nil {
+		p.zapLogger.Error("Failed to get post viewer states", zap.Error(err))
+		return err
+	}
+	for _, post := range posts {
+		state := states[post.ID]
+		isOwner := post.UserID == userID
+		post.Saved = &state.Saved
+		post.MyVote = &state.MyVote
+		post.IsOwner = &isOwner
+	}
+	return nil
+}
+
+func (p *postServiceImpl) CreatePost(post *entities.PostCreateRequest, userID string) (*model.Post, error) {
+	post.Tags = helpers.NormalizeTags(post.Tags)
 	createdPost, err := p.postRepository.Create(post, userID)
 	if err != nil {
 		p.zapLogger.Error("Failed to create post", zap.Error(err))
@@ -76,20 +155,97 @@ func (p *postServiceImpl) CreatePost(post *entities.PostCreateUpdateRequest, use
 }
 
 func (p *postServiceImpl) UpdatePost(post *entities.PostCreateUpdateRequest, postID string) (*model.Post, error) {
+	post.Tags = helpers.NormalizeTags(post.Tags)
 	updatedPost, err := p.postRepository.Update(post, postID)
 	if err != nil {
 		p.zapLogger.Error("Failed to update post", zap.Error(err))
 		return nil, err
 	}
+	go p.RefreshTopVotedCache()
 	return updatedPost, nil
 }
 
+func (p *postServiceImpl) PublishPost(postID string, publishAt *time.Time) (*model.Post, error) {
+	if err := p.postRepository.Publish(postID, publishAt); err != nil {
+		p.zapLogger.Error("Failed to publish post", zap.Error(err))
+		return nil, err
+	}
+	go p.RefreshTopVotedCache()
+	return p.GetPostByID(postID)
+}
+
+func (p *postServiceImpl) PublishScheduledPosts() error {
+	published, err := p.postRepository.PublishScheduled()
+	if err != nil {
+		p.zapLogger.Error("Failed to publish scheduled posts", zap.Error(err))
+		return err
+	}
+	if published > 0 {
+		p.zapLogger.Info("Published scheduled posts", zap.Int64("Count", published))
+		go p.RefreshTopVotedCache()
+	}
+	return nil
+}
+
+func (p *postServiceImpl) GetPostRevisions(postID string) (*[]model.PostRevision, error) {
+	revisions, err := p.postRepository.GetRevisions(postID)
+	if err != nil {
+		p.zapLogger.Error("Failed to get post revisions", zap.Error(err))
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (p *postServiceImpl) GetPostRevisionDiff(postID string, revision int) (*model.PostRevisionDiff, error) {
+	current, err := p.postRepository.GetRevision(postID, revision)
+	if err != nil {
+		p.zapLogger.Error("Failed to get post revision", zap.Error(err))
+		return nil, err
+	}
+	revisionDiff := &model.PostRevisionDiff{PostRevision: *current}
+	var previous model.PostRevision
+	if revision > 1 {
+		previousRevision, err := p.postRepository.GetRevision(postID, revision-1)
+		if err != nil {
+			p.zapLogger.Error("Failed to get previous post revision", zap.Error(err))
+			return nil, err
+		}
+		previous = *previousRevision
+		revisionDiff.PreviousRevision = &previousRevision.Revision
+	}
+	revisionDiff.TitleDiff = helpers.LineDiff(previous.Title, current.Title)
+	revisionDiff.TextDiff = helpers.LineDiff(previous.Text, current.Text)
+	return revisionDiff, nil
+}
+
 func (p *postServiceImpl) DeletePost(postID string) error {
 	err := p.postRepository.Delete(postID)
 	if err != nil {
-		p.zapLogger.Error("Failed to delete post")
+		p.zapLogger.Error("Failed to delete post", zap.Error(err))
+		return err
+	}
+	go p.RefreshTopVotedCache()
+	return nil
+}
+
+func (p *postServiceImpl) RestorePost(postID string) error {
+	if err := p.postRepository.Restore(postID, p.cfg.Posts.RestoreWindow); err != nil {
+		p.zapLogger.Error("Failed to restore post", zap.Error(err))
 		return err
 	}
+	go p.RefreshTopVotedCache()
+	return nil
+}
+
+func (p *postServiceImpl) PurgeDeletedPosts() error {
+	purged, err := p.postRepository.Purge(p.cfg.Posts.RestoreWindow)
+	if err != nil {
+		p.zapLogger.Error("Failed to purge deleted posts", zap.Error(err))
+		return err
+	}
+	if purged > 0 {
+		p.zapLogger.Info("Purged deleted posts", zap.Int64("Count", purged))
+	}
 	return nil
 }
 
@@ -98,7 +254,7 @@ func (p *postServiceImpl) AddPostVote(postID, userID, vote string) error {
 		p.zapLogger.Error("Failed to add vote on post", zap.Error(err))
 		return err
 	}
-	go p.refreshTopVotedCache()
+	go p.RefreshTopVotedCache()
 	return nil
 }
 
@@ -107,18 +263,57 @@ func (p *postServiceImpl) RemovePostVote(postID, userID string) error {
 		p.zapLogger.Error("Failed to remove vote on post", zap.Error(err))
 		return err
 	}
-	go p.refreshTopVotedCache()
+	go p.RefreshTopVotedCache()
+	return nil
+}
+
+func (p *postServiceImpl) SavePost(postID, userID string) error {
+	if err := p.postRepository.Save(postID, userID); err != nil {
+		p.zapLogger.Error("Failed to save post", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (p *postServiceImpl) UnsavePost(postID, userID string) error {
+	if err := p.postRepository.Unsave(postID, userID); err != nil {
+		p.zapLogger.Error("Failed to unsave post", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (p *postServiceImpl) ReconcileVoteCounts() (int64, error) {
+	fixed, err := p.postRepository.ReconcileVoteCounts()
+	if err != nil {
+		p.zapLogger.Error("Failed to reconcile vote counters", zap.Error(err))
+		return 0, err
+	}
+	if fixed > 0 {
+		p.zapLogger.Warn("Reconciled drifted vote counters", zap.Int64("Count", fixed))
+		p.RefreshTopVotedCache()
+	}
+	return fixed, nil
+}
+
+func (p *postServiceImpl) RefreshRisingScores() error {
+	if err := p.postRepository.UpdateRisingScores(p.cfg.Ranking.RisingWindow); err != nil {
+		p.zapLogger.Error("Failed to refresh rising scores", zap.Error(err))
+		return err
+	}
 	return nil
 }
 
-func (p *postServiceImpl) refreshTopVotedCache() {
+// RefreshTopVotedCache rebuilds the cached top 5 posts. it is called after any
+// change that can move a post into or out of the list or change how it shows.
+func (p *postServiceImpl) RefreshTopVotedCache() {
 	filter := &helpers.PaginateFilter{
 		Page:         1,
 		PageSize:     5,
 		Sort:         "-vote_count",
 		SortSafeList: []string{"vote_count", "-vote_count"},
 	}
-	posts, metadata, err := p.postRepository.GetAll(filter)
+	posts, metadata, err := p.postRepository.GetAll(filter, &entities.PostFilter{})
 	if err != nil {
 		p.zapLogger.Error("Failed to refresh top voted cache", zap.Error(err))
 		return
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"go.uber.org/zap"
+)
+
+type tagServiceImpl struct {
+	tagRepository domain.TagRepository
+	zapLogger     *zap.Logger
+}
+
+func NewTagService(tagRepository domain.TagRepository, zapLogger *zap.Logger) domain.TagService {
+	return &tagServiceImpl{
+		tagRepository: tagRepository,
+		zapLogger:     zapLogger,
+	}
+}
+
+func (t *tagServiceImpl) GetPopularTags(filter *helpers.PaginateFilter) (*[]model.Tag, helpers.Metadata, error) {
+	tags, metaData, err := t.tagRepository.GetPopular(filter)
+	if err != nil {
+		t.zapLogger.Error("Failed to get popular tags", zap.Error(err))
+		return nil, helpers.Metadata{}, err
+	}
+	return tags, metaData, nil
+}
@@ -2,33 +2,65 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"github.com/arshamroshannejad/task-rootext/config"
 	"github.com/arshamroshannejad/task-rootext/internal/domain"
 	"github.com/arshamroshannejad/task-rootext/internal/entities"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/keyring"
+	"github.com/arshamroshannejad/task-rootext/internal/mailer"
 	"github.com/arshamroshannejad/task-rootext/internal/model"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
+	"net/url"
 	"time"
 )
 
+// emailVerificationPurpose is the audience of verification tokens.
+const emailVerificationPurpose = "email_verification"
+
 type userServiceImpl struct {
-	userRepository domain.UserRepository
-	redisDB        *redis.Client
-	zapLogger      *zap.Logger
-	cfg            *config.Config
+	userRepository         domain.UserRepository
+	refreshTokenRepository domain.RefreshTokenRepository
+	sessionRepository      domain.SessionRepository
+	mfaRepository          domain.MFARepository
+	accountRepository      domain.AccountRepository
+	postService            domain.PostService
+	redisDB                *redis.Client
+	mailer                 mailer.Mailer
+	keys                   *keyring.Keyring
+	zapLogger              *zap.Logger
+	cfg                    *config.Config
 }
 
-func NewUserService(userRepository domain.UserRepository, redisDB *redis.Client, zapLogger *zap.Logger, cfg *config.Config) domain.UserService {
+func NewUserService(userRepository domain.UserRepository, refreshTokenRepository domain.RefreshTokenRepository, sessionRepository domain.SessionRepository, mfaRepository domain.MFARepository, accountRepository domain.AccountRepository, postService domain.PostService, redisDB *redis.Client, mailSender mailer.Mailer, keys *keyring.Keyring, zapLogger *zap.Logger, cfg *config.Config) domain.UserService {
 	return &userServiceImpl{
-		userRepository: userRepository,
-		redisDB:        redisDB,
-		zapLogger:      zapLogger,
-		cfg:            cfg,
+		userRepository:         userRepository,
+		refreshTokenRepository: refreshTokenRepository,
+		sessionRepository:      sessionRepository,
+		mfaRepository:          mfaRepository,
+		accountRepository:      accountRepository,
+		postService:            postService,
+		redisDB:                redisDB,
+		mailer:                 mailSender,
+		keys:                   keys,
+		zapLogger:              zapLogger,
+		cfg:                    cfg,
 	}
 }
 
+// verificationClaims is the payload of an email verification token. the subject
+// is the user and the id a nonce that makes it single-use. the email is part of
+// it, so a token stops working when the address changes.
+type verificationClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
 func (u *userServiceImpl) GetUserByID(id string) (*model.User, error) {
 	user, err := u.userRepository.GetByID(id)
 	if err != nil {
@@ -47,12 +79,40 @@ func (u *userServiceImpl) GetUserByEmail(email string) (*model.User, error) {
 	return user, nil
 }
 
-func (u *userServiceImpl) CreateUser(user *entities.UserAuthRequest) error {
-	if err := u.userRepository.Create(user); err != nil {
+func (u *userServiceImpl) GetUserByUsername(username string) (*model.User, error) {
+	user, err := u.userRepository.GetByUsername(username)
+	if err != nil {
+		u.zapLogger.Error("Failed to get user with username", zap.Error(err))
+		return nil, err
+	}
+	return user, nil
+}
+
+func (u *userServiceImpl) GetUserProfile(username string) (*model.UserProfile, error) {
+	profile, err := u.userRepository.GetProfile(username)
+	if err != nil {
+		u.zapLogger.Error("Failed to get profile of user", zap.Error(err))
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (u *userServiceImpl) UpdateUserProfile(userID string, profile *entities.UserProfileUpdateRequest) (*model.UserProfile, error) {
+	user, err := u.userRepository.UpdateProfile(userID, profile)
+	if err != nil {
+		u.zapLogger.Error("Failed to update profile of user", zap.Error(err))
+		return nil, err
+	}
+	return u.GetUserProfile(user.Username)
+}
+
+func (u *userServiceImpl) CreateUser(user *entities.UserRegisterRequest) (*model.User, error) {
+	createdUser, err := u.userRepository.Create(user)
+	if err != nil {
 		u.zapLogger.Error("Failed to create user", zap.Error(err))
-		return err
+		return nil, err
 	}
-	return nil
+	return createdUser, nil
 }
 
 func (u *userServiceImpl) EncryptPassword(plainPass string) (string, error) {
@@ -72,26 +132,485 @@ func (u *userServiceImpl) VerifyPassword(hashPass, plainPass string) error {
 	return nil
 }
 
-func (u *userServiceImpl) CreateAccessToken(userID, email string) (string, error) {
+// CreateAccessToken signs a short-lived access token for a session. the jti
+// lets revoking the session reject the token, and the token version of the user
+// lets a password change revoke every token at once. the role is carried in the
+// token as well, changing it bumps the token version so it is never stale.
+func (u *userServiceImpl) CreateAccessToken(user *model.User, jti string) (string, error) {
 	exp := time.Now().Add(u.cfg.App.AccessHourTTL).Unix()
 	claims := jwt.MapClaims{
-		"user_id": userID,
-		"email":   email,
+		"user_id": user.ID,
+		"email":   user.Email,
+		"role":    user.Role,
+		"jti":     jti,
+		"tv":      user.TokenVersion,
 		"exp":     exp,
 	}
-	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(u.cfg.App.Secret))
+	token, err := u.keys.Sign(claims)
 	if err != nil {
 		u.zapLogger.Error("Failed to create user access token", zap.Error(err))
+		return "", err
+	}
+	return token, nil
+}
+
+// ParseAccessToken checks the signature of an access token with the key of
+// the keyring it names and returns the parsed token.
+func (u *userServiceImpl) ParseAccessToken(accessToken string) (*jwt.Token, error) {
+	token, err := helpers.IsTokenValid(accessToken, u.keys.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+	// tokens of other purposes are signed with the same keys but carry an
+	// audience, access tokens never do
+	if audience, _ := token.Claims.GetAudience(); len(audience) > 0 {
+		return nil, jwt.ErrTokenInvalidAudience
 	}
 	return token, nil
 }
 
-func (u *userServiceImpl) BlockJwtToken(token string, exp float64) error {
-	remaining := time.Until(time.Unix(int64(exp), 0))
-	result := u.redisDB.Set(context.Background(), token, "blocked", remaining)
-	if result.Err() != nil {
-		u.zapLogger.Error("Failed to add token in blacklist", zap.Error(result.Err()))
-		return result.Err()
+// CreateTokenPair starts a new session for a fresh login. the jti of the
+// session is also the family of its refresh tokens.
+func (u *userServiceImpl) CreateTokenPair(user *model.User, userAgent, ip string) (*model.TokenPair, error) {
+	jti, err := helpers.RandomToken(16)
+	if err != nil {
+		u.zapLogger.Error("Failed to create session jti", zap.Error(err))
+		return nil, err
+	}
+	refreshToken, err := helpers.RandomToken(32)
+	if err != nil {
+		u.zapLogger.Error("Failed to create refresh token", zap.Error(err))
+		return nil, err
+	}
+	if err := u.sessionRepository.Create(user.ID, jti, userAgent, ip); err != nil {
+		u.zapLogger.Error("Failed to create session", zap.Error(err))
+		return nil, err
+	}
+	expiresAt := time.Now().Add(u.cfg.App.RefreshHourTTL)
+	if err := u.refreshTokenRepository.Create(user.ID, jti, helpers.HashToken(refreshToken), expiresAt); err != nil {
+		u.zapLogger.Error("Failed to store refresh token", zap.Error(err))
+		return nil, err
+	}
+	accessToken, err := u.CreateAccessToken(user, jti)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// RefreshTokenPair exchanges a refresh token for a new pair and revokes it. a
+// refresh token that was already revoked has leaked, so its whole session is
+// revoked and everyone holding a token of it has to log in again.
+func (u *userServiceImpl) RefreshTokenPair(refreshToken, ip string) (*model.TokenPair, error) {
+	stored, err := u.refreshTokenRepository.GetByHash(helpers.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrRefreshTokenInvalid
+		}
+		u.zapLogger.Error("Failed to get refresh token", zap.Error(err))
+		return nil, err
+	}
+	if stored.RevokedAt != nil {
+		return nil, u.revokeReusedSession(stored)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, domain.ErrRefreshTokenInvalid
+	}
+	user, err := u.GetUserByID(stored.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+	newRefreshToken, err := helpers.RandomToken(32)
+	if err != nil {
+		u.zapLogger.Error("Failed to create refresh token", zap.Error(err))
+		return nil, err
+	}
+	expiresAt := time.Now().Add(u.cfg.App.RefreshHourTTL)
+	if err := u.refreshTokenRepository.Rotate(stored.ID, helpers.HashToken(newRefreshToken), expiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, u.revokeReusedSession(stored)
+		}
+		u.zapLogger.Error("Failed to rotate refresh token", zap.Error(err))
+		return nil, err
+	}
+	if err := u.sessionRepository.Touch(stored.FamilyID, ip); err != nil {
+		u.zapLogger.Error("Failed to touch session", zap.Error(err))
+	}
+	accessToken, err := u.CreateAccessToken(user, stored.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TokenPair{AccessToken: accessToken, RefreshToken: newRefreshToken}, nil
+}
+
+// GetUserSessions returns the sessions of a user that are still alive. the one
+// with the given jti is marked as the current session.
+func (u *userServiceImpl) GetUserSessions(userID, currentJTI string) (*[]model.Session, error) {
+	sessions, err := u.sessionRepository.GetActiveByUser(userID)
+	if err != nil {
+		u.zapLogger.Error("Failed to get user sessions", zap.Error(err))
+		return nil, err
+	}
+	for i := range *sessions {
+		(*sessions)[i].Current = (*sessions)[i].JTI == currentJTI
+	}
+	return sessions, nil
+}
+
+func (u *userServiceImpl) RevokeSession(userID, sessionID string) error {
+	jti, err := u.sessionRepository.RevokeByID(userID, sessionID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			u.zapLogger.Error("Failed to revoke session", zap.Error(err))
+		}
+		return err
+	}
+	return u.revokeSessionTokens(jti)
+}
+
+// Logout revokes the session the request was made with.
+func (u *userServiceImpl) Logout(jti string) error {
+	if err := u.sessionRepository.RevokeByJTI(jti); err != nil {
+		u.zapLogger.Error("Failed to revoke session", zap.Error(err))
+		return err
+	}
+	return u.revokeSessionTokens(jti)
+}
+
+// IsSessionRevoked reports whether the session with the given jti was revoked
+// while its access tokens could still be valid.
+func (u *userServiceImpl) IsSessionRevoked(jti string) (bool, error) {
+	err := u.redisDB.Get(context.Background(), "session_revoked:"+jti).Err()
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, redis.Nil):
+		return false, nil
+	default:
+		u.zapLogger.Error("Failed to check session revocation", zap.Error(err))
+		return false, err
+	}
+}
+
+// revokeSessionTokens rejects the access tokens of a session until the last
+// of them expires and revokes its refresh tokens.
+func (u *userServiceImpl) revokeSessionTokens(jti string) error {
+	err := u.redisDB.Set(context.Background(), "session_revoked:"+jti, 1, u.cfg.App.AccessHourTTL).Err()
+	if err != nil {
+		u.zapLogger.Error("Failed to mark session as revoked", zap.Error(err))
+		return err
+	}
+	if err := u.refreshTokenRepository.RevokeFamily(jti); err != nil {
+		u.zapLogger.Error("Failed to revoke refresh token family", zap.Error(err))
+		return err
 	}
 	return nil
 }
+
+func (u *userServiceImpl) revokeReusedSession(token *model.RefreshToken) error {
+	u.zapLogger.Warn("Refresh token reused, revoking its session", zap.String("UserID", token.UserID), zap.String("JTI", token.FamilyID))
+	if err := u.Logout(token.FamilyID); err != nil {
+		return err
+	}
+	return domain.ErrRefreshTokenReused
+}
+
+func (u *userServiceImpl) SendVerificationEmail(user *model.User) error {
+	nonce, err := helpers.RandomToken(16)
+	if err != nil {
+		u.zapLogger.Error("Failed to create verification token nonce", zap.Error(err))
+		return err
+	}
+	claims := verificationClaims{
+		Email: user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.ID,
+			Audience:  jwt.ClaimStrings{emailVerificationPurpose},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(u.cfg.Auth.VerificationTokenTTL)),
+			ID:        nonce,
+		},
+	}
+	token, err := u.keys.Sign(claims)
+	if err != nil {
+		u.zapLogger.Error("Failed to sign verification token", zap.Error(err))
+		return err
+	}
+	link, err := url.Parse(u.cfg.Auth.VerificationURL)
+	if err != nil {
+		u.zapLogger.Error("Failed to parse verification url", zap.Error(err))
+		return err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Welcome!\n\nOpen the link below to verify your email address:\n\n%s\n\nThe link expires in %s. If you did not create an account, you can ignore this email.\n",
+			link.String(),
+			u.cfg.Auth.VerificationTokenTTL,
+		),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := u.mailer.Send(ctx, msg); err != nil {
+		u.zapLogger.Error("Failed to send verification email", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (u *userServiceImpl) ResendVerificationEmail(userID string) error {
+	user, err := u.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.IsEmailVerified() {
+		return domain.ErrEmailAlreadyVerified
+	}
+	key := "email_verification_cooldown:" + userID
+	sent, err := u.redisDB.SetNX(context.Background(), key, 1, u.cfg.Auth.VerificationCooldown).Result()
+	if err != nil {
+		u.zapLogger.Error("Failed to set verification email cooldown", zap.Error(err))
+		return err
+	}
+	if !sent {
+		return domain.ErrVerificationCooldown
+	}
+	return u.SendVerificationEmail(user)
+}
+
+// VerifyEmail marks the email of the token as verified. every token can only
+// be used once, the nonce of a used token is remembered until it expires.
+func (u *userServiceImpl) VerifyEmail(token string) error {
+	var claims verificationClaims
+	if err := u.keys.ParseFor(emailVerificationPurpose, token, &claims); err != nil {
+		return domain.ErrVerificationTokenInvalid
+	}
+	remaining := time.Until(claims.ExpiresAt.Time)
+	if remaining <= 0 {
+		return domain.ErrVerificationTokenInvalid
+	}
+	user, err := u.userRepository.GetByID(claims.Subject)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrVerificationTokenInvalid
+		}
+		u.zapLogger.Error("Failed to get user with id", zap.Error(err))
+		return err
+	}
+	if user.IsEmailVerified() {
+		return domain.ErrEmailAlreadyVerified
+	}
+	fresh, err := u.redisDB.SetNX(context.Background(), "email_verification_used:"+claims.ID, 1, remaining).Result()
+	if err != nil {
+		u.zapLogger.Error("Failed to mark verification token as used", zap.Error(err))
+		return err
+	}
+	if !fresh {
+		return domain.ErrVerificationTokenInvalid
+	}
+	if err := u.userRepository.MarkEmailVerified(claims.Subject, claims.Email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// the address changed since the token was sent
+			return domain.ErrVerificationTokenInvalid
+		}
+		u.zapLogger.Error("Failed to mark email as verified", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetTokenVersion returns the token version of a user, which JwtAuth compares
+// with every access token. it is cached in redis, so authenticating a request
+// does not need the database.
+func (u *userServiceImpl) GetTokenVersion(userID string) (int, error) {
+	key := "token_version:" + userID
+	tokenVersion, err := u.redisDB.Get(context.Background(), key).Int()
+	if err == nil {
+		return tokenVersion, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		u.zapLogger.Error("Failed to get token version from redis", zap.Error(err))
+	}
+	tokenVersion, err = u.userRepository.GetTokenVersion(userID)
+	if err != nil {
+		u.zapLogger.Error("Failed to get token version", zap.Error(err))
+		return 0, err
+	}
+	u.cacheTokenVersion(userID, tokenVersion)
+	return tokenVersion, nil
+}
+
+// RequestPasswordReset emails a reset link when an account with the address
+// exists. the work happens in the background, so neither the response nor its
+// timing tells whether the account exists.
+func (u *userServiceImpl) RequestPasswordReset(email string) {
+	go u.sendPasswordResetEmail(email)
+}
+
+// ResetPassword sets a new password with a reset token and revokes every
+// access token and refresh token issued before.
+func (u *userServiceImpl) ResetPassword(token, newPassword string) error {
+	passwordHash, err := u.EncryptPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	user, err := u.userRepository.ResetPassword(helpers.HashToken(token), passwordHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrPasswordResetTokenInvalid
+		}
+		u.zapLogger.Error("Failed to reset password", zap.Error(err))
+		return err
+	}
+	return u.revokeAllTokens(user.ID, user.TokenVersion)
+}
+
+// ChangePassword sets a new password after checking the old one and revokes
+// every token of the user. it returns a fresh token pair, so the client that
+// changed the password stays logged in.
+func (u *userServiceImpl) ChangePassword(userID, oldPassword, newPassword, userAgent, ip string) (*model.TokenPair, error) {
+	user, err := u.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.VerifyPassword(user.Password, oldPassword); err != nil {
+		return nil, domain.ErrPasswordIncorrect
+	}
+	passwordHash, err := u.EncryptPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	user, err = u.userRepository.UpdatePassword(userID, passwordHash)
+	if err != nil {
+		u.zapLogger.Error("Failed to update password", zap.Error(err))
+		return nil, err
+	}
+	if err := u.revokeAllTokens(user.ID, user.TokenVersion); err != nil {
+		return nil, err
+	}
+	return u.CreateTokenPair(user, userAgent, ip)
+}
+
+// LogoutEverywhere revokes every access token and refresh token of the user.
+func (u *userServiceImpl) LogoutEverywhere(userID string) error {
+	tokenVersion, err := u.userRepository.BumpTokenVersion(userID)
+	if err != nil {
+		u.zapLogger.Error("Failed to bump token version", zap.Error(err))
+		return err
+	}
+	return u.revokeAllTokens(userID, tokenVersion)
+}
+
+// SetUserRole changes the role of a user. the tokens of the user are revoked,
+// so they have to log in again and get a token with the new role.
+func (u *userServiceImpl) SetUserRole(userID, role string) (*model.User, error) {
+	user, err := u.userRepository.UpdateRole(userID, role)
+	if err != nil {
+		u.zapLogger.Error("Failed to update user role", zap.Error(err))
+		return nil, err
+	}
+	if err := u.revokeAllTokens(userID, user.TokenVersion); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// revokeAllTokens publishes the new token version of a user, which rejects the
+// access tokens issued before, and revokes all of their sessions and refresh tokens.
+func (u *userServiceImpl) revokeAllTokens(userID string, tokenVersion int) error {
+	if err := u.sessionRepository.RevokeAllForUser(userID); err != nil {
+		u.zapLogger.Error("Failed to revoke sessions of user", zap.Error(err))
+		return err
+	}
+	if err := u.refreshTokenRepository.RevokeAllForUser(userID); err != nil {
+		u.zapLogger.Error("Failed to revoke refresh tokens of user", zap.Error(err))
+		return err
+	}
+	return u.publishTokenVersion(userID, tokenVersion)
+}
+
+func (u *userServiceImpl) sendPasswordResetEmail(email string) {
+	user, err := u.userRepository.GetByEmail(email)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			u.zapLogger.Error("Failed to get user with email", zap.Error(err))
+		}
+		return
+	}
+	// anyone can ask for a reset, so the emails to one account are throttled
+	key := "password_reset_cooldown:" + user.ID
+	fresh, err := u.redisDB.SetNX(context.Background(), key, 1, u.cfg.Auth.PasswordResetCooldown).Result()
+	if err != nil {
+		u.zapLogger.Error("Failed to set password reset cooldown", zap.Error(err))
+		return
+	}
+	if !fresh {
+		return
+	}
+	token, err := helpers.RandomToken(32)
+	if err != nil {
+		u.zapLogger.Error("Failed to create password reset token", zap.Error(err))
+		return
+	}
+	expiresAt := time.Now().Add(u.cfg.Auth.PasswordResetTTL)
+	if err := u.userRepository.CreatePasswordResetToken(user.ID, helpers.HashToken(token), expiresAt); err != nil {
+		u.zapLogger.Error("Failed to store password reset token", zap.Error(err))
+		return
+	}
+	link, err := url.Parse(u.cfg.Auth.PasswordResetURL)
+	if err != nil {
+		u.zapLogger.Error("Failed to parse password reset url", zap.Error(err))
+		return
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password of your account.\n\nOpen the link below to choose a new password:\n\n%s\n\nThe link expires in %s. If it was not you, you can ignore this email and your password stays the same.\n",
+			link.String(),
+			u.cfg.Auth.PasswordResetTTL,
+		),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := u.mailer.Send(ctx, msg); err != nil {
+		u.zapLogger.Error("Failed to send password reset email", zap.Error(err))
+	}
+}
+
+// publishTokenVersion caches the token version of a user after it was bumped.
+// when the cache cannot be updated the cached version is dropped instead, so
+// the old one is not served for the rest of its ttl. the error is returned
+// only when even that fails.
+func (u *userServiceImpl) publishTokenVersion(userID string, tokenVersion int) error {
+	key := "token_version:" + userID
+	err := u.redisDB.Set(context.Background(), key, tokenVersion, time.Hour).Err()
+	if err == nil {
+		return nil
+	}
+	u.zapLogger.Error("Failed to cache token version", zap.Error(err))
+	if err := u.redisDB.Del(context.Background(), key).Err(); err != nil {
+		u.zapLogger.Error("Failed to drop cached token version", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// cacheTokenVersion caches a token version read from the database, only when
+// none is cached. a bump can publish a newer version between the read and this
+// write, which must not be overwritten with the older one.
+func (u *userServiceImpl) cacheTokenVersion(userID string, tokenVersion int) {
+	err := u.redisDB.SetNX(context.Background(), "token_version:"+userID, tokenVersion, time.Hour).Err()
+	if err != nil {
+		u.zapLogger.Error("Failed to cache token version", zap.Error(err))
+	}
+}
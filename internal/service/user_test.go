@@ -0,0 +1,601 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arshamroshannejad/task-rootext/config"
+	"github.com/arshamroshannejad/task-rootext/internal/domain"
+	"github.com/arshamroshannejad/task-rootext/internal/helpers"
+	"github.com/arshamroshannejad/task-rootext/internal/keyring"
+	"github.com/arshamroshannejad/task-rootext/internal/model"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// memoryRedis answers the commands of a redis client from maps, so the
+// service can be tested without a redis server. it only knows the commands
+// the tested code sends.
+type memoryRedis struct {
+	mu      sync.Mutex
+	data    map[string]string
+	hashes  map[string]map[string]string
+	expires map[string]time.Time
+}
+
+func newMemoryRedis() *memoryRedis {
+	return &memoryRedis{data: map[string]string{}, hashes: map[string]map[string]string{}, expires: map[string]time.Time{}}
+}
+
+func (m *memoryRedis) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (m *memoryRedis) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for _, cmd := range cmds {
+			// the commands of a TxPipeline come between MULTI and EXEC, which
+			// have nothing to do here
+			if cmd.Name() == "multi" || cmd.Name() == "exec" {
+				continue
+			}
+			if err := m.process(cmd); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func (m *memoryRedis) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.process(cmd)
+	}
+}
+
+// expire sets the ttl of a key, a zero ttl removes it.
+func (m *memoryRedis) expire(key string, ttl time.Duration) {
+	if ttl == 0 {
+		delete(m.expires, key)
+		return
+	}
+	m.expires[key] = time.Now().Add(ttl)
+}
+
+// exists drops the key when it expired and reports whether it is still there.
+func (m *memoryRedis) exists(key string) bool {
+	if expiresAt, ok := m.expires[key]; ok && !time.Now().Before(expiresAt) {
+		delete(m.data, key)
+		delete(m.hashes, key)
+		delete(m.expires, key)
+	}
+	_, isString := m.data[key]
+	_, isHash := m.hashes[key]
+	return isString || isHash
+}
+
+// ttlArg reads the EX or PX option of a SET.
+func ttlArg(args []any) time.Duration {
+	for i := 3; i+1 < len(args); i++ {
+		n, _ := strconv.ParseInt(fmt.Sprint(args[i+1]), 10, 64)
+		switch strings.ToLower(fmt.Sprint(args[i])) {
+		case "ex":
+			return time.Duration(n) * time.Second
+		case "px":
+			return time.Duration(n) * time.Millisecond
+		}
+	}
+	return 0
+}
+
+func (m *memoryRedis) process(cmd redis.Cmder) error {
+	args := cmd.Args()
+	key := ""
+	if len(args) > 1 {
+		key = fmt.Sprint(args[1])
+	}
+	switch c := cmd.(type) {
+	case *redis.StatusCmd:
+		if cmd.Name() != "set" {
+			break
+		}
+		delete(m.hashes, key)
+		m.data[key] = fmt.Sprint(args[2])
+		m.expire(key, ttlArg(args))
+		c.SetVal("OK")
+		return nil
+	case *redis.StringCmd:
+		if cmd.Name() != "get" {
+			break
+		}
+		value, ok := m.data[key]
+		if !m.exists(key) || !ok {
+			c.SetErr(redis.Nil)
+			return redis.Nil
+		}
+		c.SetVal(value)
+		return nil
+	case *redis.BoolCmd:
+		switch {
+		case cmd.Name() == "set" && strings.EqualFold(fmt.Sprint(args[len(args)-1]), "nx"):
+			// SET key value EX seconds NX, as sent by SetNX
+			if m.exists(key) {
+				c.SetVal(false)
+				return nil
+			}
+			m.data[key] = fmt.Sprint(args[2])
+			m.expire(key, ttlArg(args))
+			c.SetVal(true)
+			return nil
+		case cmd.Name() == "expire":
+			if !m.exists(key) {
+				c.SetVal(false)
+				return nil
+			}
+			seconds, _ := strconv.ParseInt(fmt.Sprint(args[2]), 10, 64)
+			m.expire(key, time.Duration(seconds)*time.Second)
+			c.SetVal(true)
+			return nil
+		}
+	case *redis.IntCmd:
+		switch cmd.Name() {
+		case "del":
+			var deleted int64
+			for _, arg := range args[1:] {
+				if m.exists(fmt.Sprint(arg)) {
+					delete(m.data, fmt.Sprint(arg))
+					delete(m.hashes, fmt.Sprint(arg))
+					delete(m.expires, fmt.Sprint(arg))
+					deleted++
+				}
+			}
+			c.SetVal(deleted)
+			return nil
+		case "incr":
+			m.exists(key)
+			n, _ := strconv.ParseInt(m.data[key], 10, 64)
+			m.data[key] = strconv.FormatInt(n+1, 10)
+			c.SetVal(n + 1)
+			return nil
+		case "hincrby":
+			if !m.exists(key) {
+				m.hashes[key] = map[string]string{}
+			}
+			field := fmt.Sprint(args[2])
+			n, _ := strconv.ParseInt(m.hashes[key][field], 10, 64)
+			by, _ := strconv.ParseInt(fmt.Sprint(args[3]), 10, 64)
+			m.hashes[key][field] = strconv.FormatInt(n+by, 10)
+			c.SetVal(n + by)
+			return nil
+		case "hset":
+			if !m.exists(key) {
+				m.hashes[key] = map[string]string{}
+			}
+			var added int64
+			for i := 2; i+1 < len(args); i += 2 {
+				field := fmt.Sprint(args[i])
+				if _, ok := m.hashes[key][field]; !ok {
+					added++
+				}
+				m.hashes[key][field] = fmt.Sprint(args[i+1])
+			}
+			c.SetVal(added)
+			return nil
+		}
+	case *redis.MapStringStringCmd:
+		if cmd.Name() != "hgetall" {
+			break
+		}
+		hash := map[string]string{}
+		if m.exists(key) {
+			for field, value := range m.hashes[key] {
+				hash[field] = value
+			}
+		}
+		c.SetVal(hash)
+		return nil
+	case *redis.DurationCmd:
+		if cmd.Name() != "ttl" {
+			break
+		}
+		expiresAt, ok := m.expires[key]
+		switch {
+		case !m.exists(key):
+			c.SetVal(-2)
+		case !ok:
+			c.SetVal(-1)
+		default:
+			c.SetVal(time.Until(expiresAt).Round(time.Second))
+		}
+		return nil
+	}
+	err := fmt.Errorf("memoryRedis: unsupported command %s", strings.ToUpper(cmd.Name()))
+	cmd.SetErr(err)
+	return err
+}
+
+// memoryRefreshTokens keeps refresh tokens like the refresh_tokens table.
+type memoryRefreshTokens struct {
+	tokens map[string]*model.RefreshToken
+	hashes map[string]string
+	nextID int
+	// rotateLost makes Rotate act as if a concurrent request rotated the
+	// token first.
+	rotateLost bool
+}
+
+func newMemoryRefreshTokens() *memoryRefreshTokens {
+	return &memoryRefreshTokens{tokens: map[string]*model.RefreshToken{}, hashes: map[string]string{}}
+}
+
+func (m *memoryRefreshTokens) GetByHash(tokenHash string) (*model.RefreshToken, error) {
+	id, ok := m.hashes[tokenHash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	token := *m.tokens[id]
+	return &token, nil
+}
+
+func (m *memoryRefreshTokens) Create(userID, familyID, tokenHash string, expiresAt time.Time) error {
+	m.nextID++
+	id := fmt.Sprint(m.nextID)
+	m.tokens[id] = &model.RefreshToken{ID: id, UserID: userID, FamilyID: familyID, ExpiresAt: expiresAt}
+	m.hashes[tokenHash] = id
+	return nil
+}
+
+func (m *memoryRefreshTokens) Rotate(tokenID string, tokenHash string, expiresAt time.Time) error {
+	token := m.tokens[tokenID]
+	if token.RevokedAt != nil || m.rotateLost {
+		return sql.ErrNoRows
+	}
+	now := time.Now()
+	token.RevokedAt = &now
+	return m.Create(token.UserID, token.FamilyID, tokenHash, expiresAt)
+}
+
+func (m *memoryRefreshTokens) RevokeFamily(familyID string) error {
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *memoryRefreshTokens) RevokeAllForUser(userID string) error {
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// liveTokens returns the ids of the tokens of a family that are not revoked.
+func (m *memoryRefreshTokens) liveTokens(familyID string) []string {
+	var ids []string
+	for id, token := range m.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// memorySessions records which sessions were revoked or touched.
+type memorySessions struct {
+	domain.SessionRepository
+	revoked map[string]bool
+	touched map[string]string
+}
+
+func (m *memorySessions) Touch(jti, ip string) error {
+	m.touched[jti] = ip
+	return nil
+}
+
+func (m *memorySessions) RevokeByJTI(jti string) error {
+	m.revoked[jti] = true
+	return nil
+}
+
+func (m *memorySessions) RevokeAllForUser(userID string) error {
+	m.revoked["user:"+userID] = true
+	return nil
+}
+
+type memoryUsers struct {
+	domain.UserRepository
+	users map[string]*model.User
+	// afterTokenVersionRead runs after GetTokenVersion read the version and
+	// before it returns, like a request that runs in between.
+	afterTokenVersionRead func()
+}
+
+func (m *memoryUsers) GetByEmail(email string) (*model.User, error) {
+	for _, user := range m.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryUsers) GetTokenVersion(userID string) (int, error) {
+	user, ok := m.users[userID]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	tokenVersion := user.TokenVersion
+	if m.afterTokenVersionRead != nil {
+		m.afterTokenVersionRead()
+	}
+	return tokenVersion, nil
+}
+
+func (m *memoryUsers) BumpTokenVersion(userID string) (int, error) {
+	user, ok := m.users[userID]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	user.TokenVersion++
+	return user.TokenVersion, nil
+}
+
+func (m *memoryUsers) GetByID(id string) (*model.User, error) {
+	user, ok := m.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+type refreshFixture struct {
+	service  *userServiceImpl
+	tokens   *memoryRefreshTokens
+	sessions *memorySessions
+	redis    *memoryRedis
+	users    *memoryUsers
+}
+
+const testFamily = "family-1"
+
+func newRefreshFixture(t *testing.T) *refreshFixture {
+	t.Helper()
+	cfg := &config.Config{
+		App: &config.App{Secret: strings.Repeat("s", 32), AccessHourTTL: 15 * time.Minute, RefreshHourTTL: time.Hour},
+		JWT: &config.JWT{InsecureHS256: true},
+		Auth: &config.Auth{
+			LoginMaxAttempts:     5,
+			LoginIPMaxAttempts:   20,
+			LoginAttemptWindow:   15 * time.Minute,
+			LoginBackoffBase:     time.Second,
+			LoginLockoutDuration: 15 * time.Minute,
+		},
+	}
+	keys, err := keyring.New(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("keyring.New: %v", err)
+	}
+	memRedis := newMemoryRedis()
+	redisDB := redis.NewClient(&redis.Options{Addr: "memory:0"})
+	redisDB.AddHook(memRedis)
+	t.Cleanup(func() { redisDB.Close() })
+	f := &refreshFixture{
+		tokens:   newMemoryRefreshTokens(),
+		sessions: &memorySessions{revoked: map[string]bool{}, touched: map[string]string{}},
+		redis:    memRedis,
+	}
+	f.users = &memoryUsers{users: map[string]*model.User{"7": {ID: "7", Email: "james@gmail.com", Role: "user"}}}
+	f.service = NewUserService(f.users, f.tokens, f.sessions, nil, nil, nil, redisDB, nil, keys, zap.NewNop(), cfg).(*userServiceImpl)
+	return f
+}
+
+// issue stores a refresh token of the test family and returns it.
+func (f *refreshFixture) issue(t *testing.T, userID string, expiresAt time.Time) string {
+	t.Helper()
+	token, err := helpers.RandomToken(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.tokens.Create(userID, testFamily, helpers.HashToken(token), expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRefreshTokenPair(t *testing.T) {
+	tests := []struct {
+		name string
+		// present sets up the family and returns the refresh token the client
+		// sends.
+		present func(t *testing.T, f *refreshFixture) string
+		wantErr error
+		// revoked reports whether the session ends up revoked.
+		revoked bool
+		// live is the number of usable tokens left in the family.
+		live int
+	}{
+		{
+			name: "rotates a valid token",
+			present: func(t *testing.T, f *refreshFixture) string {
+				return f.issue(t, "7", time.Now().Add(time.Hour))
+			},
+			live: 1,
+		},
+		{
+			name: "replayed token revokes its family",
+			present: func(t *testing.T, f *refreshFixture) string {
+				token := f.issue(t, "7", time.Now().Add(time.Hour))
+				if _, err := f.service.RefreshTokenPair(token, "203.0.113.9"); err != nil {
+					t.Fatalf("first refresh: %v", err)
+				}
+				return token
+			},
+			wantErr: domain.ErrRefreshTokenReused,
+			revoked: true,
+		},
+		{
+			name: "token rotated by a concurrent request revokes its family",
+			present: func(t *testing.T, f *refreshFixture) string {
+				f.tokens.rotateLost = true
+				return f.issue(t, "7", time.Now().Add(time.Hour))
+			},
+			wantErr: domain.ErrRefreshTokenReused,
+			revoked: true,
+		},
+		{
+			name: "expired token",
+			present: func(t *testing.T, f *refreshFixture) string {
+				return f.issue(t, "7", time.Now().Add(-time.Minute))
+			},
+			wantErr: domain.ErrRefreshTokenInvalid,
+			live:    1,
+		},
+		{
+			name: "unknown token",
+			present: func(t *testing.T, f *refreshFixture) string {
+				f.issue(t, "7", time.Now().Add(time.Hour))
+				return "not-a-refresh-token"
+			},
+			wantErr: domain.ErrRefreshTokenInvalid,
+			live:    1,
+		},
+		{
+			name: "token of a deleted user",
+			present: func(t *testing.T, f *refreshFixture) string {
+				return f.issue(t, "8", time.Now().Add(time.Hour))
+			},
+			wantErr: domain.ErrRefreshTokenInvalid,
+			live:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newRefreshFixture(t)
+			token := tt.present(t, f)
+			pair, err := f.service.RefreshTokenPair(token, "203.0.113.9")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RefreshTokenPair error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if pair.AccessToken == "" || pair.RefreshToken == "" || pair.RefreshToken == token {
+					t.Errorf("RefreshTokenPair = %+v, want a new access and refresh token", pair)
+				}
+				if _, err := f.tokens.GetByHash(helpers.HashToken(pair.RefreshToken)); err != nil {
+					t.Errorf("new refresh token is not stored: %v", err)
+				}
+				if ip := f.sessions.touched[testFamily]; ip != "203.0.113.9" {
+					t.Errorf("session touched from %q, want %q", ip, "203.0.113.9")
+				}
+			}
+			if got := len(f.tokens.liveTokens(testFamily)); got != tt.live {
+				t.Errorf("live tokens in family = %d, want %d", got, tt.live)
+			}
+			if f.sessions.revoked[testFamily] != tt.revoked {
+				t.Errorf("session revoked = %v, want %v", f.sessions.revoked[testFamily], tt.revoked)
+			}
+			revoked, err := f.service.IsSessionRevoked(testFamily)
+			if err != nil {
+				t.Fatalf("IsSessionRevoked: %v", err)
+			}
+			if revoked != tt.revoked {
+				t.Errorf("IsSessionRevoked = %v, want %v", revoked, tt.revoked)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenPairRotatedTokenIsSingleUse(t *testing.T) {
+	f := newRefreshFixture(t)
+	first := f.issue(t, "7", time.Now().Add(time.Hour))
+	second, err := f.service.RefreshTokenPair(first, "203.0.113.9")
+	if err != nil {
+		t.Fatalf("refresh with first token: %v", err)
+	}
+	third, err := f.service.RefreshTokenPair(second.RefreshToken, "203.0.113.9")
+	if err != nil {
+		t.Fatalf("refresh with second token: %v", err)
+	}
+	// an attacker replaying the first token burns the tokens of the legitimate
+	// client as well, who has to log in again
+	if _, err := f.service.RefreshTokenPair(first, "198.51.100.4"); !errors.Is(err, domain.ErrRefreshTokenReused) {
+		t.Fatalf("replay of first token error = %v, want %v", err, domain.ErrRefreshTokenReused)
+	}
+	if _, err := f.service.RefreshTokenPair(third.RefreshToken, "203.0.113.9"); !errors.Is(err, domain.ErrRefreshTokenReused) {
+		t.Fatalf("refresh with latest token after replay error = %v, want %v", err, domain.ErrRefreshTokenReused)
+	}
+}
+
+func TestGetTokenVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare sets up the cache and the database before the first
+		// GetTokenVersion.
+		prepare func(t *testing.T, f *refreshFixture)
+		// first is what the first GetTokenVersion returns, cached is the
+		// version it leaves in redis.
+		first  int
+		cached string
+	}{
+		{
+			name:    "reads through on a cache miss",
+			prepare: func(t *testing.T, f *refreshFixture) { f.users.users["7"].TokenVersion = 3 },
+			first:   3,
+			cached:  "3",
+		},
+		{
+			name: "serves the cached version",
+			prepare: func(t *testing.T, f *refreshFixture) {
+				f.redis.data["token_version:7"] = "4"
+				f.users.users["7"].TokenVersion = 3
+			},
+			first:  4,
+			cached: "4",
+		},
+		{
+			name: "logout everywhere between the read and the cache write",
+			prepare: func(t *testing.T, f *refreshFixture) {
+				f.users.afterTokenVersionRead = func() {
+					f.users.afterTokenVersionRead = nil
+					if err := f.service.LogoutEverywhere("7"); err != nil {
+						t.Fatalf("LogoutEverywhere: %v", err)
+					}
+				}
+			},
+			first:  0,
+			cached: "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newRefreshFixture(t)
+			tt.prepare(t, f)
+			got, err := f.service.GetTokenVersion("7")
+			if err != nil {
+				t.Fatalf("GetTokenVersion: %v", err)
+			}
+			if got != tt.first {
+				t.Errorf("GetTokenVersion = %d, want %d", got, tt.first)
+			}
+			if cached := f.redis.data["token_version:7"]; cached != tt.cached {
+				t.Errorf("cached token version = %q, want %q", cached, tt.cached)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage keeps files on the local filesystem. it is also an http.Handler
+// that serves them, so the router can expose the files it stores.
+type LocalStorage struct {
+	root      string
+	publicURL string
+}
+
+func NewLocalStorage(root, publicURL string) (*LocalStorage, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{
+		root:      root,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+	}, nil
+}
+
+func (l *LocalStorage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	name := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	// write to a temporary file first, so a failed upload never leaves a partial file behind
+	file, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), name)
+}
+
+func (l *LocalStorage) Delete(ctx context.Context, key string) error {
+	if err := os.Remove(l.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *LocalStorage) URL(key string) string {
+	return l.publicURL + "/" + key
+}
+
+// ServeHTTP serves the stored file named by the request path. the prefix the
+// handler is mounted on must be stripped first. directories are not listed.
+func (l *LocalStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := l.path(r.URL.Path)
+	info, err := os.Stat(name)
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeFile(w, r, name)
+}
+
+func (l *LocalStorage) path(key string) string {
+	// cleaning the key as an absolute path keeps it inside the root directory
+	return filepath.Join(l.root, filepath.FromSlash(path.Clean("/"+key)))
+}
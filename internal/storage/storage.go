@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/arshamroshannejad/task-rootext/config"
+	"io"
+)
+
+// Storage keeps uploaded files. keys are slash separated paths generated by
+// the application, never taken from user input.
+type Storage interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+func New(cfg *config.Config) (Storage, error) {
+	switch cfg.Storage.Driver {
+	case "local":
+		return NewLocalStorage(cfg.Storage.LocalPath, cfg.Storage.PublicURL)
+	case "s3":
+		return NewS3Storage(cfg.Storage)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
@@ -0,0 +1,3 @@
+-- Drop the tables if they already exist
+DROP TABLE IF EXISTS comment_votes;
+DROP TABLE IF EXISTS comments;
@@ -0,0 +1,20 @@
+CREATE TABLE comments (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    parent_id INTEGER REFERENCES comments(id) ON DELETE CASCADE, -- NULL for top level comments
+    user_id INTEGER REFERENCES users(id),
+    text TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_comments_post_id ON comments (post_id);
+CREATE INDEX idx_comments_parent_id ON comments (parent_id);
+
+CREATE TABLE comment_votes (
+    user_id INTEGER REFERENCES users(id),
+    comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+    vote INTEGER,
+    PRIMARY KEY (user_id, comment_id),
+    CONSTRAINT check_comment_vote_value CHECK (vote IN (-1, 1))
+);
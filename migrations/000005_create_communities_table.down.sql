@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_posts_community_id;
+ALTER TABLE posts DROP COLUMN IF EXISTS community_id;
+
+-- Drop the tables if they already exist
+DROP TABLE IF EXISTS community_moderators;
+DROP TABLE IF EXISTS communities;
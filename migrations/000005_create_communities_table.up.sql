@@ -0,0 +1,19 @@
+CREATE TABLE communities (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    description TEXT,
+    creator_id INTEGER REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE community_moderators (
+    community_id INTEGER REFERENCES communities(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (community_id, user_id)
+);
+
+-- Posts created before communities existed keep a NULL community
+ALTER TABLE posts ADD COLUMN community_id INTEGER REFERENCES communities(id);
+
+CREATE INDEX idx_posts_community_id ON posts (community_id);
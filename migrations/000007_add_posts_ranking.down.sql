@@ -0,0 +1,18 @@
+DROP INDEX IF EXISTS idx_posts_rising_score;
+DROP INDEX IF EXISTS idx_posts_controversial_score;
+DROP INDEX IF EXISTS idx_posts_best_score;
+DROP INDEX IF EXISTS idx_posts_hot_score;
+
+DROP FUNCTION IF EXISTS controversial_score(INTEGER, INTEGER);
+DROP FUNCTION IF EXISTS best_score(INTEGER, INTEGER);
+DROP FUNCTION IF EXISTS hot_score(INTEGER, INTEGER, TIMESTAMPTZ);
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS rising_score,
+    DROP COLUMN IF EXISTS controversial_score,
+    DROP COLUMN IF EXISTS best_score,
+    DROP COLUMN IF EXISTS hot_score,
+    DROP COLUMN IF EXISTS downvotes,
+    DROP COLUMN IF EXISTS upvotes;
+
+ALTER TABLE votes DROP COLUMN IF EXISTS created_at;
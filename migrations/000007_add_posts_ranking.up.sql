@@ -0,0 +1,59 @@
+-- Rising ranking needs to know when a vote was cast
+ALTER TABLE votes ADD COLUMN created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE posts
+    ADD COLUMN upvotes INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN downvotes INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN hot_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN best_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN controversial_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN rising_score DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- Time-decayed ranking: every 12.5 hours is worth a factor of ten in net votes
+CREATE FUNCTION hot_score(upvotes INTEGER, downvotes INTEGER, created_at TIMESTAMPTZ) RETURNS DOUBLE PRECISION AS $$
+    SELECT SIGN((upvotes - downvotes)::DOUBLE PRECISION) * LOG(GREATEST(ABS(upvotes - downvotes), 1)::DOUBLE PRECISION)
+        + (EXTRACT(EPOCH FROM created_at)::DOUBLE PRECISION - 1134028003) / 45000
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- Lower bound of the Wilson score confidence interval (95%) of the upvote ratio
+CREATE FUNCTION best_score(upvotes INTEGER, downvotes INTEGER) RETURNS DOUBLE PRECISION AS $$
+    SELECT CASE
+        WHEN upvotes + downvotes = 0 THEN 0
+        ELSE ((upvotes + 1.9208) / (upvotes + downvotes)
+            - 1.96 * SQRT((upvotes::DOUBLE PRECISION * downvotes) / (upvotes + downvotes) + 0.9604) / (upvotes + downvotes))
+            / (1 + 3.8416 / (upvotes + downvotes))
+    END
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- Many votes that are evenly split rank highest
+CREATE FUNCTION controversial_score(upvotes INTEGER, downvotes INTEGER) RETURNS DOUBLE PRECISION AS $$
+    SELECT CASE
+        WHEN upvotes <= 0 OR downvotes <= 0 THEN 0
+        ELSE POWER((upvotes + downvotes)::DOUBLE PRECISION, CASE
+            WHEN upvotes > downvotes THEN downvotes::DOUBLE PRECISION / upvotes
+            ELSE upvotes::DOUBLE PRECISION / downvotes
+        END)
+    END
+$$ LANGUAGE SQL IMMUTABLE;
+
+UPDATE posts p
+SET upvotes = c.upvotes, downvotes = c.downvotes
+FROM (
+    SELECT
+        post_id,
+        COUNT(*) FILTER (WHERE vote = 1)::INTEGER AS upvotes,
+        COUNT(*) FILTER (WHERE vote = -1)::INTEGER AS downvotes
+    FROM votes
+    GROUP BY post_id
+) c
+WHERE p.id = c.post_id;
+
+UPDATE posts
+SET hot_score = hot_score(upvotes, downvotes, created_at),
+    best_score = best_score(upvotes, downvotes),
+    controversial_score = controversial_score(upvotes, downvotes);
+
+CREATE INDEX idx_posts_hot_score ON posts (hot_score);
+CREATE INDEX idx_posts_best_score ON posts (best_score);
+CREATE INDEX idx_posts_controversial_score ON posts (controversial_score);
+CREATE INDEX idx_posts_rising_score ON posts (rising_score);
@@ -0,0 +1,4 @@
+ALTER TABLE posts DROP COLUMN IF EXISTS edited_at;
+
+-- Drop the table if it already exists
+DROP TABLE IF EXISTS post_revisions;
@@ -0,0 +1,16 @@
+CREATE TABLE post_revisions (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    text TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (post_id, revision)
+);
+
+-- NULL until the title or text of the post is edited for the first time
+ALTER TABLE posts ADD COLUMN edited_at TIMESTAMP WITH TIME ZONE;
+
+-- Existing posts start their history with their current content
+INSERT INTO post_revisions (post_id, revision, title, text, created_at)
+SELECT id, 1, title, text, created_at FROM posts;
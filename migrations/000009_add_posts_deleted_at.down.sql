@@ -0,0 +1,6 @@
+ALTER TABLE votes
+    DROP CONSTRAINT votes_post_id_fkey,
+    ADD CONSTRAINT votes_post_id_fkey FOREIGN KEY (post_id) REFERENCES posts(id);
+
+DROP INDEX IF EXISTS idx_posts_deleted_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS deleted_at;
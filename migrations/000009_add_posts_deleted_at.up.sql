@@ -0,0 +1,9 @@
+-- Soft deleted posts keep their row until the purge job removes them
+ALTER TABLE posts ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_posts_deleted_at ON posts (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Votes are removed together with the post they belong to
+ALTER TABLE votes
+    DROP CONSTRAINT votes_post_id_fkey,
+    ADD CONSTRAINT votes_post_id_fkey FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE;
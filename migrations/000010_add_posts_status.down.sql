@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_posts_user_id_unpublished;
+DROP INDEX IF EXISTS idx_posts_publish_at_scheduled;
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS publish_at,
+    DROP COLUMN IF EXISTS status;
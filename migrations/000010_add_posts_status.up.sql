@@ -0,0 +1,10 @@
+-- Drafts and scheduled posts stay hidden from public listings until they are published
+ALTER TABLE posts
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'published' CHECK (status IN ('draft', 'scheduled', 'published')),
+    ADD COLUMN publish_at TIMESTAMP WITH TIME ZONE;
+
+-- Existing posts were published when they were created
+UPDATE posts SET publish_at = created_at;
+
+CREATE INDEX idx_posts_publish_at_scheduled ON posts (publish_at) WHERE status = 'scheduled';
+CREATE INDEX idx_posts_user_id_unpublished ON posts (user_id) WHERE status <> 'published';
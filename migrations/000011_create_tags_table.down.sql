@@ -0,0 +1,3 @@
+-- Drop the tables if they already exist
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,15 @@
+CREATE TABLE attachments (
+    id SERIAL PRIMARY KEY,
+    -- NULL once the attachment or its post is deleted, until the cleanup job removes the stored file
+    post_id INTEGER REFERENCES posts(id) ON DELETE SET NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    storage_key VARCHAR(255) UNIQUE NOT NULL,
+    url TEXT NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(127) NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_attachments_post_id ON attachments (post_id);
+CREATE INDEX idx_attachments_detached ON attachments (id) WHERE post_id IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_posts_score;
+ALTER TABLE posts DROP COLUMN IF EXISTS score;
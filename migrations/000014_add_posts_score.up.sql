@@ -0,0 +1,16 @@
+-- Net votes are kept next to upvotes and downvotes, so reads do not have to sum the votes table
+ALTER TABLE posts ADD COLUMN score INTEGER NOT NULL DEFAULT 0;
+
+UPDATE posts p
+SET upvotes = c.upvotes, downvotes = c.downvotes, score = c.upvotes - c.downvotes
+FROM (
+    SELECT
+        post_id,
+        COUNT(*) FILTER (WHERE vote = 1)::INTEGER AS upvotes,
+        COUNT(*) FILTER (WHERE vote = -1)::INTEGER AS downvotes
+    FROM votes
+    GROUP BY post_id
+) c
+WHERE p.id = c.post_id;
+
+CREATE INDEX idx_posts_score ON posts (score);
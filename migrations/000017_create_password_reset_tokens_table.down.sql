@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN IF EXISTS token_version;
+
+-- Drop the table if it already exists
+DROP TABLE IF EXISTS password_reset_tokens;
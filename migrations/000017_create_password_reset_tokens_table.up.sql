@@ -0,0 +1,14 @@
+-- Reset tokens are only stored as SHA-256 hashes and can be used once
+CREATE TABLE password_reset_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+
+-- Access tokens carry the version they were issued with, bumping it invalidates all of them
+ALTER TABLE users ADD COLUMN token_version INT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Drop the table if it already exists
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,25 @@
+-- Every login is a session. its jti is carried by the access tokens of the login
+-- and is the family of its refresh tokens.
+CREATE TABLE sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    jti VARCHAR(32) NOT NULL UNIQUE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions (user_id) WHERE revoked_at IS NULL;
+
+-- Logins made before sessions existed are known through their refresh tokens
+INSERT INTO sessions (user_id, jti, created_at, last_seen_at, revoked_at)
+SELECT
+    user_id,
+    family_id,
+    MIN(created_at),
+    MAX(created_at),
+    CASE WHEN BOOL_AND(revoked_at IS NOT NULL) THEN MAX(revoked_at) END
+FROM refresh_tokens
+GROUP BY user_id, family_id;
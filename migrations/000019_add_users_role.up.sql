@@ -0,0 +1,2 @@
+-- Admins and moderators can act on content of other users across the whole site
+ALTER TABLE users ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin'));
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_username;
+
+ALTER TABLE users DROP COLUMN IF EXISTS bio;
+ALTER TABLE users DROP COLUMN IF EXISTS display_name;
+ALTER TABLE users DROP COLUMN IF EXISTS username;
@@ -0,0 +1,8 @@
+-- Usernames are unique regardless of case, existing users get one made from their id
+ALTER TABLE users ADD COLUMN username VARCHAR(32);
+UPDATE users SET username = 'user' || id;
+ALTER TABLE users ALTER COLUMN username SET NOT NULL;
+CREATE UNIQUE INDEX idx_users_username ON users (LOWER(username));
+
+ALTER TABLE users ADD COLUMN display_name VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN bio VARCHAR(500) NOT NULL DEFAULT '';
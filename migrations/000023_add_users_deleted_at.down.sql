@@ -0,0 +1,11 @@
+ALTER TABLE community_moderators
+    DROP CONSTRAINT community_moderators_user_id_fkey,
+    ADD CONSTRAINT community_moderators_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id);
+ALTER TABLE comment_votes
+    DROP CONSTRAINT comment_votes_user_id_fkey,
+    ADD CONSTRAINT comment_votes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id);
+ALTER TABLE votes
+    DROP CONSTRAINT votes_user_id_fkey,
+    ADD CONSTRAINT votes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id);
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,13 @@
+-- Deleted accounts are anonymized in place, so the posts and comments they wrote keep a valid author
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Votes and moderator seats belong to the account and go with it
+ALTER TABLE votes
+    DROP CONSTRAINT votes_user_id_fkey,
+    ADD CONSTRAINT votes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE comment_votes
+    DROP CONSTRAINT comment_votes_user_id_fkey,
+    ADD CONSTRAINT comment_votes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE community_moderators
+    DROP CONSTRAINT community_moderators_user_id_fkey,
+    ADD CONSTRAINT community_moderators_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1,2 @@
+-- The revisions of unpublished edits are gone, there is nothing to restore
+SELECT 1;
@@ -0,0 +1,14 @@
+-- Revisions are only recorded while a post is published, drafts and scheduled posts have none
+DELETE FROM post_revisions r USING posts p WHERE r.post_id = p.id AND p.status <> 'published';
+
+-- Published posts keep the revision they were published with and the ones after it
+DELETE FROM post_revisions r USING posts p
+WHERE r.post_id = p.id AND r.revision < (
+    SELECT COALESCE(MAX(pr.revision), 1) FROM post_revisions pr WHERE pr.post_id = p.id AND pr.created_at <= p.publish_at
+);
+
+-- Renumber them from 1, through negative numbers so the unique (post_id, revision) never collides
+UPDATE post_revisions r SET revision = -n.revision
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY post_id ORDER BY revision) AS revision FROM post_revisions) n
+WHERE r.id = n.id;
+UPDATE post_revisions SET revision = -revision;